@@ -0,0 +1,152 @@
+package osgb
+
+import (
+	"math"
+)
+
+const (
+	// OS quote OSTN02 and OSTN15 as matching the ETRS89 to OSGB36 National Grid
+	// relationship to 0.1m RMS within the onshore polygon.
+	onshoreHorizontalAccuracy = 0.1
+	// OSGM02 and OSGM15 are quoted as 0.02m RMS on the mainland.
+	mainlandVerticalAccuracy = 0.02
+	// Offshore distances are only searched for this far from a position.
+	maxOffshoreSearch = 50 // km
+)
+
+// Accuracy is an estimate of the accuracy of a transformed position, from the
+// figures OS publish for the grids. OS publish no accuracy for offshore positions,
+// nor a vertical accuracy for the island geoid regions, so these are NaN.
+type Accuracy struct {
+	// Horizontal accuracy in metres, NaN if unknown
+	Horizontal float64
+	// Vertical accuracy in metres, NaN if unknown
+	Vertical float64
+	// OffshoreDistance is the distance in metres to the nearest onshore grid record,
+	// zero when the position lies in a grid cell with an onshore corner, and +Inf
+	// when there is none within 50km.
+	OffshoreDistance float64
+}
+
+func isOnshore(region GeoidRegion) bool {
+	return region != Region_OUTSIDE_BOUNDARY &&
+		region != Region_OFFSHORE &&
+		region != Region_OUTSIDE_TRANSFORMATION
+}
+
+// ToNationalGridWithAccuracy coverts a coordinate position from ETRS89 to OSGB36/ODN,
+// also estimating the accuracy of the result.
+func (tr *GridTransformer) ToNationalGridWithAccuracy(c *ETRS89Coordinate) (*OSGB36Coordinate, *Accuracy, error) {
+	etrs89Coord := etrs89ToPlaneCoord(c)
+	osgb36Coord, odnHeight, region, err := tr.toOSGB36(&etrs89Coord, c.Height)
+	if err != nil {
+		return nil, nil, err
+	}
+	acc, err := tr.accuracy(&etrs89Coord)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &OSGB36Coordinate{
+		Easting:       osgb36Coord.easting,
+		Northing:      osgb36Coord.northing,
+		Height:        odnHeight,
+		VerticalDatum: regionVerticalDatums[region],
+	}, acc, nil
+}
+
+// FromNationalGridWithAccuracy coverts a coordinate position from OSGB36/ODN to ETRS89,
+// also estimating the accuracy of the result.
+func (tr *GridTransformer) FromNationalGridWithAccuracy(c *OSGB36Coordinate) (*ETRS89Coordinate, *Accuracy, error) {
+	etrs89PlaneCoord, etrs89Height, err := tr.fromOSGB36(&planeCoord{
+		easting:  c.Easting,
+		northing: c.Northing,
+	}, c.Height, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tr.checkVerticalDatum(&etrs89PlaneCoord, c.VerticalDatum); err != nil {
+		return nil, nil, err
+	}
+	acc, err := tr.accuracy(&etrs89PlaneCoord)
+	if err != nil {
+		return nil, nil, err
+	}
+	etrs89Coord, err := etrs89FromPlaneCoord(&etrs89PlaneCoord, etrs89Height)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &etrs89Coord, acc, nil
+}
+
+// accuracy estimates the accuracy of a transformation at an ETRS89 grid position.
+func (tr *GridTransformer) accuracy(etrs89Coord *planeCoord) (*Accuracy, error) {
+	if err := tr.acquireGrid(); err != nil {
+		return nil, err
+	}
+	defer tr.releaseGrid()
+	rs, err := tr.getShiftRecords(etrs89Coord)
+	if err != nil {
+		return nil, err
+	}
+
+	onshore := false
+	vertical := mainlandVerticalAccuracy
+	for _, rec := range [...]record{rs.s0, rs.s1, rs.s2, rs.s3} {
+		if !isOnshore(rec.geoidRegion) {
+			continue
+		}
+		onshore = true
+		if rec.geoidRegion != Region_UK_MAINLAND {
+			vertical = math.NaN()
+		}
+	}
+	if onshore {
+		return &Accuracy{
+			Horizontal: onshoreHorizontalAccuracy,
+			Vertical:   vertical,
+		}, nil
+	}
+	distance, err := tr.onshoreDistance(etrs89Coord)
+	if err != nil {
+		return nil, err
+	}
+	return &Accuracy{
+		Horizontal:       math.NaN(),
+		Vertical:         math.NaN(),
+		OffshoreDistance: distance,
+	}, nil
+}
+
+// onshoreDistance returns the distance in metres from an ETRS89 grid position
+// to the nearest onshore grid record, or +Inf if there is none within maxOffshoreSearch.
+func (tr *GridTransformer) onshoreDistance(etrs89Coord *planeCoord) (float64, error) {
+	eastIndex := int(eastingIndex(etrs89Coord.easting))
+	northIndex := int(northingIndex(etrs89Coord.northing))
+
+	best := math.Inf(1)
+	for ring := 0; ring <= maxOffshoreSearch; ring++ {
+		// Every record in this ring is at least ring km away.
+		if float64(ring)*1000.0 > best {
+			break
+		}
+		for e := eastIndex - ring; e <= eastIndex+ring+1; e++ {
+			for n := northIndex - ring; n <= northIndex+ring+1; n++ {
+				onRing := e == eastIndex-ring || e == eastIndex+ring+1 ||
+					n == northIndex-ring || n == northIndex+ring+1
+				if !onRing || e < 0 || n < 0 || e >= nEastIndices || n >= nNorthIndices {
+					continue
+				}
+				rec := tr.record(uint32(e + n*nEastIndices))
+				if !isOnshore(rec.geoidRegion) {
+					continue
+				}
+				d := math.Hypot(float64(rec.etrs89Easting)-etrs89Coord.easting,
+					float64(rec.etrs89Northing)-etrs89Coord.northing)
+				if d < best {
+					best = d
+				}
+			}
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,134 @@
+package osgb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAccuracy(t *testing.T) {
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		name               string
+		coord              *ETRS89Coordinate
+		expectedHorizontal float64
+		expectedVertical   float64
+		offshore           bool
+	}{
+		// TP02, mainland
+		{
+			name:               "TP02",
+			coord:              &ETRS89Coordinate{Lat: 49.96006137820, Lon: -5.20304609998, Height: 124.269},
+			expectedHorizontal: onshoreHorizontalAccuracy,
+			expectedVertical:   mainlandVerticalAccuracy,
+		},
+		// TP01, Scilly Isles
+		{
+			name:               "TP01",
+			coord:              &ETRS89Coordinate{Lat: 49.92226393730, Lon: -6.29977752014, Height: 100.0},
+			expectedHorizontal: onshoreHorizontalAccuracy,
+			expectedVertical:   math.NaN(),
+		},
+		// TP31, offshore near St Kilda
+		{
+			name:     "TP31",
+			coord:    &ETRS89Coordinate{Lat: 57.81351838410, Lon: -8.57854456076, Height: 100.001},
+			offshore: true,
+		},
+	}
+
+	for _, d := range testData {
+		osgb36Coord, acc, err := trans.ToNationalGridWithAccuracy(d.coord)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", d.name, err)
+			continue
+		}
+		if d.offshore {
+			if acc.OffshoreDistance <= 0 {
+				t.Errorf("%s: expected offshore distance, actual %f", d.name, acc.OffshoreDistance)
+			}
+			if !math.IsNaN(acc.Horizontal) || !math.IsNaN(acc.Vertical) {
+				t.Errorf("%s: expected unknown accuracy, actual %#v", d.name, acc)
+			}
+		} else {
+			checkAccuracy(t, d.name+" horizontal accuracy", d.expectedHorizontal, acc.Horizontal)
+			checkAccuracy(t, d.name+" vertical accuracy", d.expectedVertical, acc.Vertical)
+			checkDistance(t, d.name+" offshore distance", 0, acc.OffshoreDistance)
+		}
+
+		_, inverseAcc, err := trans.FromNationalGridWithAccuracy(osgb36Coord)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", d.name, err)
+			continue
+		}
+		if !equalAccuracy(inverseAcc.Horizontal, acc.Horizontal) || !equalAccuracy(inverseAcc.Vertical, acc.Vertical) ||
+			inverseAcc.OffshoreDistance != acc.OffshoreDistance {
+			t.Errorf("%s: expected inverse accuracy %#v, actual %#v", d.name, acc, inverseAcc)
+		}
+	}
+}
+
+// equalAccuracy compares accuracies, which are NaN where unknown.
+func equalAccuracy(expected, actual float64) bool {
+	return expected == actual || math.IsNaN(expected) && math.IsNaN(actual)
+}
+
+func checkAccuracy(t *testing.T, name string, expected, actual float64) {
+	if !equalAccuracy(expected, actual) {
+		t.Errorf("%s: expected %f, actual %f", name, expected, actual)
+	}
+}
+
+func TestAccuracyUnknown(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		switch {
+		case e < 300:
+			return Region_UK_MAINLAND
+		case e < 350:
+			return Region_ORKNEY_ISLES
+		}
+		return Region_OFFSHORE
+	})
+	for _, d := range []struct {
+		coord                      planeCoord
+		horizontal, vertical, dist float64
+	}{
+		{planeCoord{easting: 100500, northing: 200500}, onshoreHorizontalAccuracy, mainlandVerticalAccuracy, 0},
+		{planeCoord{easting: 320500, northing: 200500}, onshoreHorizontalAccuracy, math.NaN(), 0},
+		{planeCoord{easting: 360000, northing: 200000}, math.NaN(), math.NaN(), 11000},
+	} {
+		acc, err := tr.accuracy(&d.coord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkAccuracy(t, "horizontal accuracy", d.horizontal, acc.Horizontal)
+		checkAccuracy(t, "vertical accuracy", d.vertical, acc.Vertical)
+		checkDistance(t, "offshore distance", d.dist, acc.OffshoreDistance)
+	}
+}
+
+func TestOnshoreDistance(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		if e == 100 && n == 200 {
+			return Region_ORKNEY_ISLES
+		}
+		return Region_OFFSHORE
+	})
+
+	distance, err := tr.onshoreDistance(&planeCoord{easting: 103000, northing: 204000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "onshore distance", 5000, distance)
+
+	distance, err = tr.onshoreDistance(&planeCoord{easting: 100000 + (maxOffshoreSearch+2)*1000, northing: 200000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(distance, 1) {
+		t.Errorf("expected no onshore record in range, actual %f", distance)
+	}
+}
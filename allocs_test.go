@@ -0,0 +1,132 @@
+package osgb
+
+import (
+	"testing"
+)
+
+var allocsTestCoord = ETRS89Coordinate{Lon: -2.5, Lat: 54, Height: 100}
+
+// Benchmark results are stored so the conversions are not optimised away.
+var (
+	osgb36Result    *OSGB36Coordinate
+	etrs89Result    *ETRS89Coordinate
+	osgb36ValResult OSGB36Coordinate
+	etrs89ValResult ETRS89Coordinate
+)
+
+func TestValueMethods(t *testing.T) {
+	tr := syntheticTransformer()
+	expected, err := tr.ToNationalGrid(&allocsTestCoord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := tr.ToNationalGridValue(allocsTestCoord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != *expected {
+		t.Errorf("expected %+v, actual %+v", *expected, actual)
+	}
+
+	expectedETRS89, err := tr.FromNationalGrid(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualETRS89, err := tr.FromNationalGridValue(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualETRS89 != *expectedETRS89 {
+		t.Errorf("expected %+v, actual %+v", *expectedETRS89, actualETRS89)
+	}
+
+	if _, err := tr.ToNationalGridValue(ETRS89Coordinate{Lon: -2, Lat: 70}); err != ErrPointOutsidePolygon {
+		t.Errorf("expected %v, actual %v", ErrPointOutsidePolygon, err)
+	}
+}
+
+func TestValueMethodsAllocs(t *testing.T) {
+	iterative, inverse := syntheticTransformer(), syntheticTransformer()
+	inverse.useInverseGrid = true
+	inverse.inverseShifts()
+	for _, interpolation := range []Interpolation{InterpolationBilinear, InterpolationBicubic, InterpolationBiquadratic} {
+		iterative.interpolation = interpolation
+		inverse.interpolation = interpolation
+		for _, tr := range []*GridTransformer{iterative, inverse} {
+			osgb36Coord, err := tr.ToNationalGridValue(allocsTestCoord)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allocs := testing.AllocsPerRun(100, func() {
+				tr.ToNationalGridValue(allocsTestCoord)
+			}); allocs != 0 {
+				t.Errorf("%s ToNationalGridValue: expected 0 allocations, actual %.1f", interpolation, allocs)
+			}
+			if allocs := testing.AllocsPerRun(100, func() {
+				tr.FromNationalGridValue(osgb36Coord)
+			}); allocs != 0 {
+				t.Errorf("%s FromNationalGridValue: expected 0 allocations, actual %.1f", interpolation, allocs)
+			}
+		}
+	}
+}
+
+func BenchmarkToNationalGrid(b *testing.B) {
+	var tr *GridTransformer
+	tr = syntheticTransformer()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if osgb36Result, err = tr.ToNationalGrid(&allocsTestCoord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToNationalGridValue(b *testing.B) {
+	var tr *GridTransformer
+	tr = syntheticTransformer()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if osgb36ValResult, err = tr.ToNationalGridValue(allocsTestCoord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFromNationalGrid(b *testing.B) {
+	var tr *GridTransformer
+	tr = syntheticTransformer()
+	c, err := tr.ToNationalGrid(&allocsTestCoord)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if etrs89Result, err = tr.FromNationalGrid(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFromNationalGridValue(b *testing.B) {
+	var tr *GridTransformer
+	tr = syntheticTransformer()
+	c, err := tr.ToNationalGridValue(allocsTestCoord)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if etrs89ValResult, err = tr.FromNationalGridValue(c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
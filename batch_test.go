@@ -0,0 +1,87 @@
+package osgb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToNationalGridBatch(t *testing.T) {
+	tr := syntheticTransformer()
+	lons := []float64{-2.5, -2, 1.5, -4}
+	lats := []float64{54, 70, 52.5, 57}
+	heights := []float64{100, 0, 20, -5}
+	n := len(lons)
+	eastings, northings, odnHeights := make([]float64, n), make([]float64, n), make([]float64, n)
+	if err := tr.ToNationalGridBatch(lons, lats, heights, eastings, northings, odnHeights); err != nil {
+		t.Fatal(err)
+	}
+	for i := range lons {
+		expected, err := tr.ToNationalGridValue(ETRS89Coordinate{Lon: lons[i], Lat: lats[i], Height: heights[i]})
+		if err != nil {
+			if !math.IsNaN(eastings[i]) || !math.IsNaN(northings[i]) || !math.IsNaN(odnHeights[i]) {
+				t.Errorf("%d: expected NaN for %v, actual (%f, %f, %f)", i, err, eastings[i], northings[i], odnHeights[i])
+			}
+			continue
+		}
+		checkDistance(t, "Easting", expected.Easting, eastings[i])
+		checkDistance(t, "Northing", expected.Northing, northings[i])
+		checkDistance(t, "Height", expected.Height, odnHeights[i])
+	}
+	if !math.IsNaN(eastings[1]) {
+		t.Errorf("expected NaN outside the grid, actual %f", eastings[1])
+	}
+
+	// The outputs may overwrite the inputs
+	expectedEasting := eastings[0]
+	if err := tr.ToNationalGridBatch(lons, lats, heights, lons, lats, heights); err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "Easting in place", expectedEasting, lons[0])
+
+	if err := tr.ToNationalGridBatch(lons, lats[:1], heights, eastings, northings, odnHeights); err != ErrBatchLength {
+		t.Errorf("expected %v, actual %v", ErrBatchLength, err)
+	}
+}
+
+// batchBenchmarkPoints returns positions spread over England and Wales.
+func batchBenchmarkPoints(n int) ([]float64, []float64, []float64) {
+	lons, lats, heights := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range lons {
+		lons[i] = -4 + 5*float64(i%1000)/1000
+		lats[i] = 51 + 3*float64(i/1000%1000)/1000
+		heights[i] = 50
+	}
+	return lons, lats, heights
+}
+
+func BenchmarkToNationalGridBatch(b *testing.B) {
+	tr := syntheticTransformer()
+	const n = 1 << 16
+	lons, lats, heights := batchBenchmarkPoints(n)
+	eastings, northings, odnHeights := make([]float64, n), make([]float64, n), make([]float64, n)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := tr.ToNationalGridBatch(lons, lats, heights, eastings, northings, odnHeights); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)*n/b.Elapsed().Seconds(), "points/s")
+}
+
+func BenchmarkToNationalGridPoints(b *testing.B) {
+	tr := syntheticTransformer()
+	const n = 1 << 16
+	lons, lats, heights := batchBenchmarkPoints(n)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range lons {
+			var err error
+			if osgb36ValResult, err = tr.ToNationalGridValue(ETRS89Coordinate{Lon: lons[j], Lat: lats[j], Height: heights[j]}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N)*n/b.Elapsed().Seconds(), "points/s")
+}
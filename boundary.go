@@ -0,0 +1,416 @@
+package osgb
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// The coverage is traced on a 500m lattice: each 1km grid cell is split into
+// quarters, each belonging to the geoid region of its nearest record as
+// returned by RegionAt.
+const (
+	quarterSize      = 500.0
+	nEastQuarters    = 2 * (nEastIndices - 1)
+	nNorthQuarters   = 2 * (nNorthIndices - 1)
+	nEastLatticeLine = nEastQuarters + 1
+)
+
+// Boundary tracing directions, anticlockwise from east.
+const (
+	dirEast = iota
+	dirNorth
+	dirWest
+	dirSouth
+)
+
+var (
+	dirDeltaX = [4]int{1, 0, -1, 0}
+	dirDeltaY = [4]int{0, 1, 0, -1}
+)
+
+// OSGB36Polygon is a polygon in OSGB36 National Grid coordinates. The first ring
+// is the exterior, anticlockwise, followed by any holes, clockwise. Each ring
+// repeats its first position at the end.
+type OSGB36Polygon [][]OSGB36Coordinate
+
+// ETRS89Polygon is a polygon in ETRS89 coordinates. The first ring is the
+// exterior, anticlockwise, followed by any holes, clockwise. Each ring repeats
+// its first position at the end.
+type ETRS89Polygon [][]ETRS89Coordinate
+
+// CoverageArea is the part of the transformation coverage belonging to one geoid region.
+type CoverageArea struct {
+	Region GeoidRegion
+	OSGB36 []OSGB36Polygon
+	ETRS89 []ETRS89Polygon
+}
+
+// Coverage is the area over which a transformer is valid, split by geoid region.
+type Coverage []CoverageArea
+
+type latticeRing []latticePoint
+
+type latticePoint struct {
+	x, y int
+}
+
+// Coverage traces the grid cells that can be transformed into polygons, one
+// CoverageArea per geoid region present in the grid. Regions are separated
+// along the same nearest record boundaries used by RegionAt, to a resolution of 500m.
+func (tr *GridTransformer) Coverage() (Coverage, error) {
+	if err := tr.acquireGrid(); err != nil {
+		return nil, err
+	}
+	defer tr.releaseGrid()
+	labels, err := tr.quarterLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	regions := map[GeoidRegion]bool{}
+	for _, label := range labels {
+		if label >= 0 {
+			regions[GeoidRegion(label)] = true
+		}
+	}
+	sorted := make([]GeoidRegion, 0, len(regions))
+	for region := range regions {
+		sorted = append(sorted, region)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	coverage := Coverage{}
+	for _, region := range sorted {
+		area := CoverageArea{Region: region}
+		for _, polygon := range groupRings(traceRings(labels, int8(region))) {
+			osgb36Polygon := make(OSGB36Polygon, 0, len(polygon))
+			etrs89Polygon := make(ETRS89Polygon, 0, len(polygon))
+			for _, ring := range polygon {
+				osgb36Ring, etrs89Ring, err := tr.transformRing(labels, ring)
+				if err != nil {
+					return nil, err
+				}
+				osgb36Polygon = append(osgb36Polygon, osgb36Ring)
+				etrs89Polygon = append(etrs89Polygon, etrs89Ring)
+			}
+			area.OSGB36 = append(area.OSGB36, osgb36Polygon)
+			area.ETRS89 = append(area.ETRS89, etrs89Polygon)
+		}
+		coverage = append(coverage, area)
+	}
+	return coverage, nil
+}
+
+// quarterLabels returns the geoid region of every quarter cell of the grid,
+// or -1 where the cell is outside the grid or the transformation.
+func (tr *GridTransformer) quarterLabels() ([]int8, error) {
+	labels := make([]int8, nEastQuarters*nNorthQuarters)
+	for i := range labels {
+		labels[i] = -1
+	}
+	for n := 0; n < nNorthIndices-1; n++ {
+		for e := 0; e < nEastIndices-1; e++ {
+			rs, err := tr.cellShiftRecords(uint32(e), uint32(n))
+			if err == ErrPointOutsidePolygon || err == ErrPointOutsideTransformation {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			qx, qy := 2*e, 2*n
+			labels[qx+qy*nEastQuarters] = int8(rs.s0.geoidRegion)
+			labels[qx+1+qy*nEastQuarters] = int8(rs.s1.geoidRegion)
+			labels[qx+1+(qy+1)*nEastQuarters] = int8(rs.s2.geoidRegion)
+			labels[qx+(qy+1)*nEastQuarters] = int8(rs.s3.geoidRegion)
+		}
+	}
+	return labels, nil
+}
+
+func quarterLabel(labels []int8, qx, qy int) int8 {
+	if qx < 0 || qy < 0 || qx >= nEastQuarters || qy >= nNorthQuarters {
+		return -1
+	}
+	return labels[qx+qy*nEastQuarters]
+}
+
+// traceRings returns the boundary rings of the quarter cells with a label, with
+// the labelled area on the left: exteriors anticlockwise and holes clockwise.
+func traceRings(labels []int8, label int8) []latticeRing {
+	// Outgoing boundary edge directions from each lattice vertex, as a bitmask.
+	edges := map[int]uint8{}
+	addEdge := func(x, y, dir int) {
+		edges[x+y*nEastLatticeLine] |= 1 << uint(dir)
+	}
+	for qy := 0; qy < nNorthQuarters; qy++ {
+		for qx := 0; qx < nEastQuarters; qx++ {
+			if labels[qx+qy*nEastQuarters] != label {
+				continue
+			}
+			if quarterLabel(labels, qx, qy-1) != label {
+				addEdge(qx, qy, dirEast)
+			}
+			if quarterLabel(labels, qx+1, qy) != label {
+				addEdge(qx+1, qy, dirNorth)
+			}
+			if quarterLabel(labels, qx, qy+1) != label {
+				addEdge(qx+1, qy+1, dirWest)
+			}
+			if quarterLabel(labels, qx-1, qy) != label {
+				addEdge(qx, qy+1, dirSouth)
+			}
+		}
+	}
+
+	starts := make([]int, 0, len(edges))
+	for v := range edges {
+		starts = append(starts, v)
+	}
+	sort.Ints(starts)
+
+	rings := []latticeRing{}
+	for _, start := range starts {
+		for edges[start] != 0 {
+			x, y := start%nEastLatticeLine, start/nEastLatticeLine
+			dir := lowestDirection(edges[start])
+			ring := latticeRing{}
+			for {
+				v := x + y*nEastLatticeLine
+				edges[v] &^= 1 << uint(dir)
+				ring = append(ring, latticePoint{x, y})
+				x += dirDeltaX[dir]
+				y += dirDeltaY[dir]
+				v = x + y*nEastLatticeLine
+				if v == start {
+					break
+				}
+				// Prefer turning left so cells touching at a corner form separate rings.
+				out := edges[v]
+				for _, turn := range []int{1, 0, 3} {
+					if next := (dir + turn) % 4; out&(1<<uint(next)) != 0 {
+						dir = next
+						break
+					}
+				}
+			}
+			rings = append(rings, simplifyRing(ring))
+		}
+	}
+	return rings
+}
+
+func lowestDirection(bits uint8) int {
+	for dir := 0; dir < 4; dir++ {
+		if bits&(1<<uint(dir)) != 0 {
+			return dir
+		}
+	}
+	return -1
+}
+
+// simplifyRing removes vertices lying on a straight line between their neighbours.
+func simplifyRing(ring latticeRing) latticeRing {
+	res := latticeRing{}
+	for i, p := range ring {
+		prev := ring[(i+len(ring)-1)%len(ring)]
+		next := ring[(i+1)%len(ring)]
+		collinear := (p.x-prev.x)*(next.y-p.y)-(p.y-prev.y)*(next.x-p.x) == 0
+		if !collinear {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func (ring latticeRing) area() int {
+	area := 0
+	for i, p := range ring {
+		next := ring[(i+1)%len(ring)]
+		area += p.x*next.y - next.x*p.y
+	}
+	return area / 2
+}
+
+// contains reports whether a point lies inside the ring, using ray casting
+// through the centre of the quarter cell above and to the right of it.
+func (ring latticeRing) contains(p latticePoint) bool {
+	px, py := float64(p.x)+0.5, float64(p.y)+0.5
+	inside := false
+	for i, a := range ring {
+		b := ring[(i+1)%len(ring)]
+		ay, by := float64(a.y), float64(b.y)
+		if (ay > py) != (by > py) {
+			x := float64(a.x) + (py-ay)/(by-ay)*float64(b.x-a.x)
+			if px < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// groupRings assigns each hole to the smallest exterior containing it.
+func groupRings(rings []latticeRing) [][]latticeRing {
+	polygons := [][]latticeRing{}
+	for _, ring := range rings {
+		if ring.area() > 0 {
+			polygons = append(polygons, []latticeRing{ring})
+		}
+	}
+	for _, ring := range rings {
+		if ring.area() > 0 {
+			continue
+		}
+		best := -1
+		for i, polygon := range polygons {
+			exterior := polygon[0]
+			if !exterior.contains(ring[0]) {
+				continue
+			}
+			if best < 0 || exterior.area() < polygons[best][0].area() {
+				best = i
+			}
+		}
+		if best >= 0 {
+			polygons[best] = append(polygons[best], ring)
+		}
+	}
+	return polygons
+}
+
+// transformRing converts a lattice ring to closed OSGB36 and ETRS89 rings.
+func (tr *GridTransformer) transformRing(labels []int8, ring latticeRing) ([]OSGB36Coordinate, []ETRS89Coordinate, error) {
+	osgb36Ring := make([]OSGB36Coordinate, 0, len(ring)+1)
+	etrs89Ring := make([]ETRS89Coordinate, 0, len(ring)+1)
+	for i := 0; i <= len(ring); i++ {
+		p := ring[i%len(ring)]
+		etrs89Coord := &planeCoord{
+			easting:  float64(p.x) * quarterSize,
+			northing: float64(p.y) * quarterSize,
+		}
+		rs, err := tr.vertexShiftRecords(labels, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		shiftEast, shiftNorth, _ := rs.shifts(etrs89Coord)
+		osgb36Ring = append(osgb36Ring, OSGB36Coordinate{
+			Easting:  etrs89Coord.easting + shiftEast,
+			Northing: etrs89Coord.northing + shiftNorth,
+		})
+		etrs89, err := etrs89FromPlaneCoord(etrs89Coord, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		etrs89Ring = append(etrs89Ring, etrs89)
+	}
+	return osgb36Ring, etrs89Ring, nil
+}
+
+// vertexShiftRecords returns the records of a transformable grid cell touching
+// a lattice vertex. Shifts are continuous across cells, so any will do.
+func (tr *GridTransformer) vertexShiftRecords(labels []int8, p latticePoint) (shiftRecords, error) {
+	var err error
+	for _, q := range []latticePoint{{p.x, p.y}, {p.x - 1, p.y}, {p.x, p.y - 1}, {p.x - 1, p.y - 1}} {
+		if quarterLabel(labels, q.x, q.y) < 0 {
+			continue
+		}
+		var rs shiftRecords
+		rs, err = tr.cellShiftRecords(uint32(q.x/2), uint32(q.y/2))
+		if err == nil {
+			return rs, nil
+		}
+	}
+	if err == nil {
+		err = ErrPointOutsideTransformation
+	}
+	return shiftRecords{}, err
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	CRS      *geoJSONCRS      `json:"crs,omitempty"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONCRS struct {
+	Type       string            `json:"type"`
+	Properties map[string]string `json:"properties"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates [][][][]float64 `json:"coordinates"`
+}
+
+// OSGB36GeoJSON encodes the coverage as a GeoJSON FeatureCollection of MultiPolygons
+// in OSGB36 National Grid eastings and northings, one feature per geoid region.
+// The collection carries a named EPSG:27700 crs member, as GeoJSON only
+// defines WGS84 longitude and latitude coordinates.
+func (c Coverage) OSGB36GeoJSON() ([]byte, error) {
+	return c.geoJSON(&geoJSONCRS{
+		Type:       "name",
+		Properties: map[string]string{"name": "urn:ogc:def:crs:EPSG::27700"},
+	}, func(area CoverageArea) [][][][]float64 {
+		res := make([][][][]float64, 0, len(area.OSGB36))
+		for _, polygon := range area.OSGB36 {
+			rings := make([][][]float64, 0, len(polygon))
+			for _, ring := range polygon {
+				positions := make([][]float64, 0, len(ring))
+				for _, p := range ring {
+					positions = append(positions, []float64{p.Easting, p.Northing})
+				}
+				rings = append(rings, positions)
+			}
+			res = append(res, rings)
+		}
+		return res
+	})
+}
+
+// ETRS89GeoJSON encodes the coverage as a GeoJSON FeatureCollection of MultiPolygons
+// in ETRS89 longitude and latitude, one feature per geoid region.
+func (c Coverage) ETRS89GeoJSON() ([]byte, error) {
+	return c.geoJSON(nil, func(area CoverageArea) [][][][]float64 {
+		res := make([][][][]float64, 0, len(area.ETRS89))
+		for _, polygon := range area.ETRS89 {
+			rings := make([][][]float64, 0, len(polygon))
+			for _, ring := range polygon {
+				positions := make([][]float64, 0, len(ring))
+				for _, p := range ring {
+					positions = append(positions, []float64{p.Lon, p.Lat})
+				}
+				rings = append(rings, positions)
+			}
+			res = append(res, rings)
+		}
+		return res
+	})
+}
+
+func (c Coverage) geoJSON(crs *geoJSONCRS, coordinates func(CoverageArea) [][][][]float64) ([]byte, error) {
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		CRS:      crs,
+		Features: make([]geoJSONFeature, 0, len(c)),
+	}
+	for _, area := range c {
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Properties: map[string]interface{}{
+				"region": uint8(area.Region),
+				"name":   area.Region.String(),
+			},
+			Geometry: geoJSONGeometry{
+				Type:        "MultiPolygon",
+				Coordinates: coordinates(area),
+			},
+		})
+	}
+	return json.Marshal(collection)
+}
@@ -0,0 +1,114 @@
+package osgb
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTraceCoverage(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		switch {
+		case e < 100 || e > 300 || n < 100 || n > 300:
+			return Region_OUTSIDE_TRANSFORMATION
+		// Loch in the mainland
+		case e >= 180 && e <= 190 && n >= 180 && n <= 190:
+			return Region_OFFSHORE
+		// Mainland
+		case e >= 150 && e <= 250 && n >= 150 && n <= 250:
+			return Region_UK_MAINLAND
+		// Two islands touching at a corner
+		case e == 120 && n == 120, e == 121 && n == 121:
+			return Region_ORKNEY_ISLES
+		}
+		return Region_OFFSHORE
+	})
+
+	labels, err := tr.quarterLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := map[int8]int{}
+	for _, label := range labels {
+		cells[label]++
+	}
+
+	for _, region := range []GeoidRegion{Region_UK_MAINLAND, Region_OFFSHORE, Region_ORKNEY_ISLES} {
+		polygons := groupRings(traceRings(labels, int8(region)))
+		area := 0
+		for _, polygon := range polygons {
+			for _, ring := range polygon {
+				area += ring.area()
+			}
+		}
+		if area != cells[int8(region)] {
+			t.Errorf("%s: expected area of %d quarter cells, actual %d", region, cells[int8(region)], area)
+		}
+		switch region {
+		case Region_UK_MAINLAND:
+			if len(polygons) != 1 || len(polygons[0]) != 2 {
+				t.Errorf("%s: expected one polygon with one hole, actual %v", region, polygons)
+			}
+		case Region_ORKNEY_ISLES:
+			if len(polygons) != 2 {
+				t.Errorf("%s: expected two polygons, actual %d", region, len(polygons))
+			}
+		}
+	}
+
+	coverage, err := tr.Coverage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coverage) != 3 {
+		t.Fatalf("expected 3 coverage areas, actual %d", len(coverage))
+	}
+	mainland := coverage[0]
+	checkRegion(t, "first coverage area", Region_UK_MAINLAND, mainland.Region)
+	exterior := mainland.OSGB36[0][0]
+	if first, last := exterior[0], exterior[len(exterior)-1]; first != last {
+		t.Errorf("expected closed ring, actual first %#v last %#v", first, last)
+	}
+	// With zero shifts the OSGB36 ring follows the 500m lattice.
+	checkDistance(t, "mainland exterior easting", 149500, exterior[0].Easting)
+	checkDistance(t, "mainland exterior northing", 149500, exterior[0].Northing)
+}
+
+func Test15Coverage_GeoJSON(t *testing.T) {
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coverage, err := trans.Coverage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regions := map[GeoidRegion]bool{}
+	for _, area := range coverage {
+		regions[area.Region] = true
+		if len(area.OSGB36) != len(area.ETRS89) {
+			t.Errorf("%s: expected matching OSGB36 and ETRS89 polygons", area.Region)
+		}
+	}
+	for _, region := range []GeoidRegion{Region_UK_MAINLAND, Region_SCILLY_ISLES, Region_ISLE_OF_MAN,
+		Region_OUTER_HEBRIDES, Region_SHETLAND_ISLES, Region_ORKNEY_ISLES, Region_OFFSHORE} {
+		if !regions[region] {
+			t.Errorf("missing coverage area for %s", region)
+		}
+	}
+
+	for _, encode := range []func() ([]byte, error){coverage.OSGB36GeoJSON, coverage.ETRS89GeoJSON} {
+		data, err := encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var collection geoJSONFeatureCollection
+		if err := json.Unmarshal(data, &collection); err != nil {
+			t.Fatal(err)
+		}
+		if len(collection.Features) != len(coverage) {
+			t.Errorf("expected %d features, actual %d", len(coverage), len(collection.Features))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package osgb
+
+import (
+	"sync/atomic"
+)
+
+// cellCacheSize is the number of grid cells a cell cache holds. Cells are
+// cached by their record index, so neighbouring cells along a row, and the
+// cells of a few tracks processed at once, rarely evict each other.
+const cellCacheSize = 64
+
+// cell is a grid cell's records, with the coefficients of its bilinear shift
+// surfaces f(t, u) = f0 + ft*t + fu*u + ftu*t*u, where t and u are the
+// position within the cell in kilometres.
+type cell struct {
+	index    uint32
+	rs       shiftRecords
+	offshore bool
+	east     [4]float64
+	north    [4]float64
+	geoid    [4]float64
+}
+
+func newCell(index uint32, rs shiftRecords) *cell {
+	c := &cell{
+		index:    index,
+		rs:       rs,
+		offshore: rs.offshore(),
+	}
+	c.east = bilinearCoefficients(rs.s0.ostnEastShift, rs.s1.ostnEastShift, rs.s2.ostnEastShift, rs.s3.ostnEastShift)
+	c.north = bilinearCoefficients(rs.s0.ostnNorthShift, rs.s1.ostnNorthShift, rs.s2.ostnNorthShift, rs.s3.ostnNorthShift)
+	c.geoid = bilinearCoefficients(rs.s0.ostnGeoidHeight, rs.s1.ostnGeoidHeight, rs.s2.ostnGeoidHeight, rs.s3.ostnGeoidHeight)
+	return c
+}
+
+// bilinearCoefficients expands the bilinear interpolation of values at the
+// corners S0 to S3 of a cell into polynomial coefficients.
+func bilinearCoefficients(s0, s1, s2, s3 float64) [4]float64 {
+	return [4]float64{s0, s1 - s0, s3 - s0, s0 - s1 + s2 - s3}
+}
+
+// shifts evaluates the cell's shift surfaces at an ETRS89 grid position, as shiftRecords.shifts.
+func (c *cell) shifts(etrs89Coord *planeCoord) (float64, float64, float64) {
+	t := (etrs89Coord.easting - float64(c.rs.s0.etrs89Easting)) / 1000.0
+	u := (etrs89Coord.northing - float64(c.rs.s0.etrs89Northing)) / 1000.0
+	tu := t * u
+	return c.east[0] + c.east[1]*t + c.east[2]*u + c.east[3]*tu,
+		c.north[0] + c.north[1]*t + c.north[2]*u + c.north[3]*tu,
+		c.geoid[0] + c.geoid[1]*t + c.geoid[2]*u + c.geoid[3]*tu
+}
+
+// cellCache holds recently used grid cells in slots chosen by record index.
+// Cells are immutable once stored, so the cache is safe for concurrent use.
+type cellCache struct {
+	slots [cellCacheSize]atomic.Value
+}
+
+func (cc *cellCache) get(index uint32) *cell {
+	c, _ := cc.slots[index%cellCacheSize].Load().(*cell)
+	if c == nil || c.index != index {
+		return nil
+	}
+	return c
+}
+
+func (cc *cellCache) put(c *cell) {
+	cc.slots[c.index%cellCacheSize].Store(c)
+}
+
+// gridShifts returns the records surrounding an ETRS89 grid position and the
+// shifts interpolated at it, applying the transformer's offshore policy. With a
+// cell cache and bilinear interpolation, cells are looked up in the cache first.
+// Callers reading a mapped grid must hold a reference taken with acquireGrid.
+func (tr *GridTransformer) gridShifts(etrs89Coord *planeCoord) (shiftRecords, float64, float64, float64, error) {
+	eastIndex, northIndex := eastingIndex(etrs89Coord.easting), northingIndex(etrs89Coord.northing)
+	if tr.cells == nil || tr.interpolation != InterpolationBilinear ||
+		eastIndex >= nEastIndices-1 || northIndex >= nNorthIndices-1 {
+		rs, err := tr.getShiftRecords(etrs89Coord)
+		if err != nil {
+			return shiftRecords{}, 0, 0, 0, err
+		}
+		shiftEast, shiftNorth, geoidHeight, err := tr.interpolate(etrs89Coord, &rs)
+		if err != nil {
+			return shiftRecords{}, 0, 0, 0, err
+		}
+		return rs, shiftEast, shiftNorth, geoidHeight, nil
+	}
+
+	index := eastIndex + northIndex*nEastIndices
+	c := tr.cells.get(index)
+	if c == nil {
+		rs, err := tr.cellShiftRecords(eastIndex, northIndex)
+		if err != nil {
+			return shiftRecords{}, 0, 0, 0, err
+		}
+		c = newCell(index, rs)
+		tr.cells.put(c)
+	}
+	if tr.offshorePolicy != OffshoreTransform && c.offshore {
+		return shiftRecords{}, 0, 0, 0, ErrPointOffshore
+	}
+	shiftEast, shiftNorth, geoidHeight := c.shifts(etrs89Coord)
+	return c.rs, shiftEast, shiftNorth, geoidHeight, nil
+}
@@ -0,0 +1,137 @@
+package osgb
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+// cacheTestTransformers returns transformers on a synthetic grid with smoothly
+// varying shifts, with and without a cell cache.
+func cacheTestTransformers() (*GridTransformer, *GridTransformer) {
+	uncached, cached := syntheticTransformer(), syntheticTransformer()
+	cached.cells = &cellCache{}
+	return uncached, cached
+}
+
+// track returns positions 10m apart heading north east from an ETRS89 position.
+func track(lon, lat float64, n int) []ETRS89Coordinate {
+	coords := make([]ETRS89Coordinate, n)
+	for i := range coords {
+		coords[i] = ETRS89Coordinate{Lon: lon + float64(i)*0.0001, Lat: lat + float64(i)*0.00006, Height: 50}
+	}
+	return coords
+}
+
+func checkCachedTransform(t *testing.T, uncached, cached *GridTransformer, c ETRS89Coordinate) {
+	expected, err := uncached.ToNationalGridValue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := cached.ToNationalGridValue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(expected.Easting-actual.Easting) > 1e-9 ||
+		math.Abs(expected.Northing-actual.Northing) > 1e-9 ||
+		math.Abs(expected.Height-actual.Height) > 1e-9 ||
+		expected.VerticalDatum != actual.VerticalDatum {
+		t.Errorf("%+v: expected %+v, actual %+v", c, expected, actual)
+	}
+	expectedETRS89, err := uncached.FromNationalGridValue(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualETRS89, err := cached.FromNationalGridValue(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(expectedETRS89.Lon-actualETRS89.Lon) > 1e-12 ||
+		math.Abs(expectedETRS89.Lat-actualETRS89.Lat) > 1e-12 ||
+		math.Abs(expectedETRS89.Height-actualETRS89.Height) > 1e-9 {
+		t.Errorf("%+v: expected %+v, actual %+v", expected, expectedETRS89, actualETRS89)
+	}
+}
+
+func TestCellCache(t *testing.T) {
+	uncached, cached := cacheTestTransformers()
+	// Each pass is repeated to check both cache misses and hits
+	for pass := 0; pass < 2; pass++ {
+		for _, c := range track(-2.5, 54, 500) {
+			checkCachedTransform(t, uncached, cached, c)
+		}
+	}
+	if _, err := cached.ToNationalGridValue(ETRS89Coordinate{Lon: -2, Lat: 70}); err != ErrPointOutsidePolygon {
+		t.Errorf("expected %v, actual %v", ErrPointOutsidePolygon, err)
+	}
+
+	osgb36Coord, err := cached.ToNationalGridValue(allocsTestCoord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		cached.ToNationalGridValue(allocsTestCoord)
+		cached.FromNationalGridValue(osgb36Coord)
+	}); allocs != 0 {
+		t.Errorf("expected 0 allocations for cached cells, actual %.1f", allocs)
+	}
+}
+
+func TestCellCacheOffshore(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		if e >= 400 {
+			return Region_OFFSHORE
+		}
+		return Region_UK_MAINLAND
+	})
+	tr.offshorePolicy = OffshoreReject
+	tr.cells = &cellCache{}
+	for i := 0; i < 2; i++ {
+		if _, err := tr.ToNationalGridValue(ETRS89Coordinate{Lon: 1.5, Lat: 52.5}); err != ErrPointOffshore {
+			t.Errorf("expected %v, actual %v", ErrPointOffshore, err)
+		}
+		if _, err := tr.ToNationalGridValue(ETRS89Coordinate{Lon: -3, Lat: 52.5}); err != nil {
+			t.Errorf("expected onshore position to transform, actual %v", err)
+		}
+	}
+}
+
+func TestCellCacheConcurrent(t *testing.T) {
+	uncached, cached := cacheTestTransformers()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for _, c := range track(-4+0.01*float64(g), 53, 200) {
+				checkCachedTransform(t, uncached, cached, c)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func benchmarkTrack(b *testing.B, tr *GridTransformer) {
+	coords := track(-2.5, 54, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range coords {
+			var err error
+			if osgb36ValResult, err = tr.ToNationalGridValue(c); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N)*float64(len(coords))/b.Elapsed().Seconds(), "points/s")
+}
+
+func BenchmarkTrack(b *testing.B) {
+	uncached, _ := cacheTestTransformers()
+	benchmarkTrack(b, uncached)
+}
+
+func BenchmarkTrackCellCache(b *testing.B) {
+	_, cached := cacheTestTransformers()
+	benchmarkTrack(b, cached)
+}
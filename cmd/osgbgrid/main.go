@@ -0,0 +1,115 @@
+// Command osgbgrid writes the OSTN transformation grid, or the part of it
+// covering a bounding box, as a binary grid file for osgb.NewMappedTransformer.
+// With -metadata it instead prints the grid's metadata and checksum.
+//
+// Usage:
+//
+//	osgbgrid [-model ostn15|ostn02] [-bbox minx,miny,maxx,maxy] [-crs etrs89|osgb36] -o file
+//	osgbgrid [-model ostn15|ostn02] -metadata
+//
+// The bounding box is given as longitudes and latitudes in degrees for ETRS89,
+// or as eastings and northings in metres for OSGB36.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mjjbell/go-osgb"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("osgbgrid: ")
+	model := flag.String("model", "ostn15", "transformation grid, ostn15 or ostn02")
+	bbox := flag.String("bbox", "", "bounding box minx,miny,maxx,maxy; the whole grid if empty")
+	crs := flag.String("crs", "etrs89", "coordinate system of the bounding box, etrs89 or osgb36")
+	out := flag.String("o", "", "output file")
+	metadata := flag.Bool("metadata", false, "print the grid metadata instead of writing a file")
+	flag.Parse()
+	if (*out == "") == !*metadata || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var tr *osgb.GridTransformer
+	var err error
+	switch *model {
+	case "ostn15":
+		tr, err = osgb.NewOSTN15Transformer()
+	case "ostn02":
+		tr, err = osgb.NewOSTN02Transformer()
+	default:
+		log.Fatalf("unknown model %q", *model)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *metadata {
+		printMetadata(tr.Metadata())
+		return
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeGrid(tr, f, *bbox, *crs); err != nil {
+		f.Close()
+		os.Remove(*out)
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeGrid(tr *osgb.GridTransformer, f *os.File, bbox, crs string) error {
+	if bbox == "" {
+		return tr.WriteBinaryGrid(f)
+	}
+	box, err := parseBoundingBox(bbox)
+	if err != nil {
+		return err
+	}
+	switch crs {
+	case "etrs89":
+		return tr.WriteBinarySubGrid(f,
+			&osgb.ETRS89Coordinate{Lon: box[0], Lat: box[1]},
+			&osgb.ETRS89Coordinate{Lon: box[2], Lat: box[3]})
+	case "osgb36":
+		return tr.WriteBinarySubGridNationalGrid(f,
+			&osgb.OSGB36Coordinate{Easting: box[0], Northing: box[1]},
+			&osgb.OSGB36Coordinate{Easting: box[2], Northing: box[3]})
+	}
+	return fmt.Errorf("unknown coordinate system %q", crs)
+}
+
+func printMetadata(md osgb.GridMetadata) {
+	fmt.Printf("model:        %s\n", md.Model)
+	fmt.Printf("version:      %s\n", md.Version)
+	fmt.Printf("release date: %s\n", md.ReleaseDate)
+	fmt.Printf("records:      %d\n", md.Records)
+	fmt.Printf("extent:       %.0f,%.0f,%.0f,%.0f\n", md.MinEasting, md.MinNorthing, md.MaxEasting, md.MaxNorthing)
+	fmt.Printf("sha256:       %s\n", md.Checksum)
+}
+
+func parseBoundingBox(s string) ([4]float64, error) {
+	var box [4]float64
+	fields := strings.Split(s, ",")
+	if len(fields) != len(box) {
+		return box, fmt.Errorf("bounding box %q must have four values", s)
+	}
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return box, fmt.Errorf("bounding box %q: %w", s, err)
+		}
+		box[i] = v
+	}
+	return box, nil
+}
@@ -49,6 +49,10 @@ type OSGB36Coordinate struct {
 	Northing float64
 	// Height in metres
 	Height float64
+	// VerticalDatum of the height. Transformations to OSGB36 set it to the datum of
+	// the position's geoid region. When set on a coordinate transformed from
+	// OSGB36, the position must lie in that datum's region.
+	VerticalDatum VerticalDatum
 }
 
 // NewOSGB36Coord creates a new coordinate position in the OSGB36/ODN geodetic datum.
@@ -106,3 +110,8 @@ func radiansToDegrees(rad float64) float64 {
 func degreesToRadians(degrees float64) float64 {
 	return degrees * radianInDegrees
 }
+
+// finite reports whether x is neither NaN nor infinite.
+func finite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
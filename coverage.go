@@ -0,0 +1,131 @@
+package osgb
+
+import "fmt"
+
+// CoverageStatus describes whether a position can be transformed.
+type CoverageStatus int
+
+const (
+	// CoverageUnknown indicates the grid could not be read to find the coverage
+	// of the position, for instance because a MappedTransformer has been closed.
+	CoverageUnknown CoverageStatus = iota
+	// CoverageOnshore indicates the position lies in a grid cell with no offshore corners.
+	CoverageOnshore
+	// CoverageOffshore indicates the position lies in a grid cell with an offshore corner.
+	// It can be transformed unless the transformer rejects offshore positions.
+	CoverageOffshore
+	// CoverageOutsidePolygon indicates transforming the position would return ErrPointOutsidePolygon.
+	CoverageOutsidePolygon
+	// CoverageOutsideTransformation indicates transforming the position would return ErrPointOutsideTransformation.
+	CoverageOutsideTransformation
+)
+
+func (s CoverageStatus) String() string {
+	switch s {
+	case CoverageUnknown:
+		return "unknown"
+	case CoverageOnshore:
+		return "onshore"
+	case CoverageOffshore:
+		return "offshore"
+	case CoverageOutsidePolygon:
+		return "outside polygon"
+	case CoverageOutsideTransformation:
+		return "outside transformation"
+	}
+	return fmt.Sprintf("CoverageStatus(%d)", int(s))
+}
+
+// Estimating the ETRS89 position of an OSGB36 position takes two iterations to
+// place it to within a few millimetres, which is enough to find its grid cell.
+const coverageIterations = 2
+
+// Covers reports whether an ETRS89 position can be transformed, without transforming it.
+func (tr *GridTransformer) Covers(c *ETRS89Coordinate) CoverageStatus {
+	etrs89Coord := etrs89ToPlaneCoord(c)
+	return tr.coverage(&etrs89Coord)
+}
+
+// CoversNationalGrid reports whether an OSGB36 position can be transformed, without transforming it.
+func (tr *GridTransformer) CoversNationalGrid(c *OSGB36Coordinate) CoverageStatus {
+	etrs89Coord, err := tr.estimateETRS89(c)
+	if err != nil {
+		return coverageStatus(err)
+	}
+	return tr.coverage(etrs89Coord)
+}
+
+// RegionAt returns the geoid region nearest an ETRS89 position.
+func (tr *GridTransformer) RegionAt(c *ETRS89Coordinate) (GeoidRegion, error) {
+	etrs89Coord := etrs89ToPlaneCoord(c)
+	return tr.region(&etrs89Coord)
+}
+
+// RegionAtNationalGrid returns the geoid region nearest an OSGB36 position.
+func (tr *GridTransformer) RegionAtNationalGrid(c *OSGB36Coordinate) (GeoidRegion, error) {
+	etrs89Coord, err := tr.estimateETRS89(c)
+	if err != nil {
+		return 0, err
+	}
+	return tr.region(etrs89Coord)
+}
+
+func (tr *GridTransformer) coverage(etrs89Coord *planeCoord) CoverageStatus {
+	if err := tr.acquireGrid(); err != nil {
+		return coverageStatus(err)
+	}
+	defer tr.releaseGrid()
+	rs, err := tr.lookupShiftRecords(etrs89Coord)
+	if err != nil {
+		return coverageStatus(err)
+	}
+	if rs.offshore() {
+		return CoverageOffshore
+	}
+	return CoverageOnshore
+}
+
+func (tr *GridTransformer) region(etrs89Coord *planeCoord) (GeoidRegion, error) {
+	if err := tr.acquireGrid(); err != nil {
+		return 0, err
+	}
+	defer tr.releaseGrid()
+	rs, err := tr.lookupShiftRecords(etrs89Coord)
+	if err != nil {
+		return 0, err
+	}
+	return nearestGeoidRegion(etrs89Coord, &rs), nil
+}
+
+// estimateETRS89 approximates the ETRS89 grid position of an OSGB36 position
+// with a fixed number of shift iterations, ignoring the offshore policy.
+func (tr *GridTransformer) estimateETRS89(c *OSGB36Coordinate) (*planeCoord, error) {
+	if err := tr.acquireGrid(); err != nil {
+		return nil, err
+	}
+	defer tr.releaseGrid()
+	etrs89Coord := &planeCoord{
+		easting:  c.Easting,
+		northing: c.Northing,
+	}
+	for i := 0; i < coverageIterations; i++ {
+		rs, err := tr.lookupShiftRecords(etrs89Coord)
+		if err != nil {
+			return nil, err
+		}
+		shiftEast, shiftNorth, _ := rs.shifts(etrs89Coord)
+		etrs89Coord.easting = c.Easting - shiftEast
+		etrs89Coord.northing = c.Northing - shiftNorth
+	}
+	return etrs89Coord, nil
+}
+
+func coverageStatus(err error) CoverageStatus {
+	switch err {
+	case ErrPointOutsideTransformation:
+		return CoverageOutsideTransformation
+	case ErrPointOutsidePolygon:
+		return CoverageOutsidePolygon
+	}
+	return CoverageUnknown
+}
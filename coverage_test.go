@@ -0,0 +1,96 @@
+package osgb
+
+import (
+	"testing"
+)
+
+func Test15Coverage(t *testing.T) {
+	inputs, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pointID, input := range inputs {
+		output, ok := outputs[pointID]
+		if !ok {
+			t.Fatal("missing point ID in output ", pointID)
+		}
+
+		etrs89Coord := &ETRS89Coordinate{
+			Lat:    input.etrs89Lat,
+			Lon:    input.etrs89Lon,
+			Height: input.etrs89Height,
+		}
+		osgb36Coord := &OSGB36Coordinate{
+			Easting:  output.osgb36Easting,
+			Northing: output.osgb36Northing,
+			Height:   output.odnHeight,
+		}
+
+		status := trans.Covers(etrs89Coord)
+		if status != CoverageOnshore && status != CoverageOffshore {
+			t.Errorf("point ID %s: expected point to be covered, actual %s", pointID, status)
+		}
+		if output.geoidModelID == Region_OFFSHORE && status != CoverageOffshore {
+			t.Errorf("point ID %s: expected offshore, actual %s", pointID, status)
+		}
+		if nationalGridStatus := trans.CoversNationalGrid(osgb36Coord); nationalGridStatus != status {
+			t.Errorf("point ID %s: expected national grid coverage %s, actual %s", pointID, status, nationalGridStatus)
+		}
+
+		region, err := trans.RegionAt(etrs89Coord)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+		checkRegion(t, pointID+" region", output.geoidModelID, region)
+
+		region, err = trans.RegionAtNationalGrid(osgb36Coord)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+		checkRegion(t, pointID+" national grid region", output.geoidModelID, region)
+	}
+}
+
+func TestCoverageOutside(t *testing.T) {
+	trans15, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trans02, err := NewOSTN02Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dublin. On the grid but out of transformation range.
+	dublin := &ETRS89Coordinate{Lat: 53.3498, Lon: -6.2603}
+	if status := trans15.Covers(dublin); status != CoverageOutsideTransformation {
+		t.Errorf("expected %s, actual %s", CoverageOutsideTransformation, status)
+	}
+	if _, err := trans15.RegionAt(dublin); err != ErrPointOutsideTransformation {
+		t.Errorf("expected ErrPointOutsideTransformation, actual %v", err)
+	}
+
+	// North Sea, well outside the OSTN02 polygon.
+	northSea := &ETRS89Coordinate{Lat: 55.0, Lon: 3.0}
+	if status := trans02.Covers(northSea); status != CoverageOutsidePolygon {
+		t.Errorf("expected %s, actual %s", CoverageOutsidePolygon, status)
+	}
+
+	offGrid := &OSGB36Coordinate{Easting: -651409.792, Northing: -313177.448}
+	if status := trans02.CoversNationalGrid(offGrid); status != CoverageOutsidePolygon {
+		t.Errorf("expected %s, actual %s", CoverageOutsidePolygon, status)
+	}
+}
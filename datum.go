@@ -0,0 +1,248 @@
+package osgb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnknownEllipsoid indicates no ellipsoid in the registry has the given name or EPSG code.
+	ErrUnknownEllipsoid = errors.New("unknown ellipsoid")
+	// ErrUnknownDatum indicates no datum in the registry has the given name or EPSG code.
+	ErrUnknownDatum = errors.New("unknown datum")
+)
+
+// Ellipsoid is a reference ellipsoid approximating the shape of the earth.
+type Ellipsoid struct {
+	Name string
+	// EPSG code of the ellipsoid
+	EPSG int
+	// SemiMajorAxis in metres
+	SemiMajorAxis float64
+	// SemiMinorAxis in metres
+	SemiMinorAxis float64
+}
+
+// Registered ellipsoids. They are values, so changing one does not change the
+// registry or the projections and datums built on it.
+var (
+	EllipsoidAiry = Ellipsoid{
+		Name:          "Airy 1830",
+		EPSG:          7001,
+		SemiMajorAxis: airyEllipsoid.semiMajorAxis,
+		SemiMinorAxis: airyEllipsoid.semiMinorAxis,
+	}
+	EllipsoidAiryModified = Ellipsoid{
+		Name:          "Airy Modified 1849",
+		EPSG:          7002,
+		SemiMajorAxis: 6377340.189,
+		SemiMinorAxis: 6356034.447,
+	}
+	EllipsoidGRS80 = Ellipsoid{
+		Name:          "GRS 1980",
+		EPSG:          7019,
+		SemiMajorAxis: grs80Ellipsoid.semiMajorAxis,
+		SemiMinorAxis: grs80Ellipsoid.semiMinorAxis,
+	}
+	EllipsoidWGS84 = Ellipsoid{
+		Name:          "WGS 84",
+		EPSG:          7030,
+		SemiMajorAxis: 6378137.000,
+		SemiMinorAxis: 6356752.314245,
+	}
+	EllipsoidInternational1924 = Ellipsoid{
+		Name:          "International 1924",
+		EPSG:          7022,
+		SemiMajorAxis: 6378388.000,
+		SemiMinorAxis: 6356911.946128,
+	}
+	EllipsoidClarke1866 = Ellipsoid{
+		Name:          "Clarke 1866",
+		EPSG:          7008,
+		SemiMajorAxis: 6378206.400,
+		SemiMinorAxis: 6356583.800,
+	}
+
+	ellipsoids = []Ellipsoid{
+		EllipsoidAiry,
+		EllipsoidAiryModified,
+		EllipsoidGRS80,
+		EllipsoidWGS84,
+		EllipsoidInternational1924,
+		EllipsoidClarke1866,
+	}
+)
+
+func (e Ellipsoid) ellipsoid() *ellipsoid {
+	return &ellipsoid{
+		semiMajorAxis: e.SemiMajorAxis,
+		semiMinorAxis: e.SemiMinorAxis,
+	}
+}
+
+// EllipsoidByName returns the registered ellipsoid with a name, ignoring case.
+func EllipsoidByName(name string) (Ellipsoid, error) {
+	for _, e := range ellipsoids {
+		if strings.EqualFold(e.Name, name) {
+			return e, nil
+		}
+	}
+	return Ellipsoid{}, fmt.Errorf("%w %q", ErrUnknownEllipsoid, name)
+}
+
+// EllipsoidByEPSG returns the registered ellipsoid with an EPSG code.
+func EllipsoidByEPSG(code int) (Ellipsoid, error) {
+	for _, e := range ellipsoids {
+		if e.EPSG == code {
+			return e, nil
+		}
+	}
+	return Ellipsoid{}, fmt.Errorf("%w EPSG:%d", ErrUnknownEllipsoid, code)
+}
+
+// HelmertParameters are the parameters of a 7 parameter Helmert transformation,
+// in the position vector convention used by OS and by PROJ's towgs84.
+type HelmertParameters struct {
+	// Translations in metres
+	Tx, Ty, Tz float64
+	// Rotations in arc seconds
+	Rx, Ry, Rz float64
+	// Scale difference in parts per million
+	Scale float64
+}
+
+func (p HelmertParameters) helmert() *helmert {
+	return &helmert{
+		tx: p.Tx,
+		ty: p.Ty,
+		tz: p.Tz,
+		d:  p.Scale * 1e-6,
+		rx: p.Rx * 1000 * milliArcSecondInRadians,
+		ry: p.Ry * 1000 * milliArcSecondInRadians,
+		rz: p.Rz * 1000 * milliArcSecondInRadians,
+	}
+}
+
+// Datum is a geodetic datum, identified by the EPSG code of its geographic coordinate
+// reference system.
+type Datum struct {
+	Name string
+	// EPSG code of the datum's geographic 2D coordinate reference system
+	EPSG      int
+	Ellipsoid Ellipsoid
+	// ToWGS84 transforms Cartesian coordinates on the datum to WGS84. Its accuracy
+	// varies between datums, from metres for OSGB36 to tens of metres for ED50.
+	ToWGS84 HelmertParameters
+}
+
+// Registered datums. They are values, so changing one does not change the
+// registry or the transformations using it.
+var (
+	DatumOSGB36 = Datum{
+		Name:      "OSGB36",
+		EPSG:      4277,
+		Ellipsoid: EllipsoidAiry,
+		ToWGS84:   HelmertParameters{Tx: 446.448, Ty: -125.157, Tz: 542.060, Rx: 0.1502, Ry: 0.2470, Rz: 0.8421, Scale: -20.4894},
+	}
+	// DatumETRS89 is treated as coincident with WGS84, as it was at 1989.0.
+	DatumETRS89 = Datum{
+		Name:      "ETRS89",
+		EPSG:      4258,
+		Ellipsoid: EllipsoidGRS80,
+	}
+	DatumWGS84 = Datum{
+		Name:      "WGS84",
+		EPSG:      4326,
+		Ellipsoid: EllipsoidWGS84,
+	}
+	DatumTM65 = Datum{
+		Name:      "TM65",
+		EPSG:      4299,
+		Ellipsoid: EllipsoidAiryModified,
+		ToWGS84:   HelmertParameters{Tx: 482.5, Ty: -130.6, Tz: 564.6, Rx: -1.042, Ry: -0.214, Rz: -0.631, Scale: 8.15},
+	}
+	DatumTM75 = Datum{
+		Name:      "TM75",
+		EPSG:      4300,
+		Ellipsoid: EllipsoidAiryModified,
+		ToWGS84:   HelmertParameters{Tx: 482.5, Ty: -130.6, Tz: 564.6, Rx: -1.042, Ry: -0.214, Rz: -0.631, Scale: 8.15},
+	}
+	DatumED50 = Datum{
+		Name:      "ED50",
+		EPSG:      4230,
+		Ellipsoid: EllipsoidInternational1924,
+		ToWGS84:   HelmertParameters{Tx: -87, Ty: -98, Tz: -121},
+	}
+	DatumNAD27 = Datum{
+		Name:      "NAD27",
+		EPSG:      4267,
+		Ellipsoid: EllipsoidClarke1866,
+		ToWGS84:   HelmertParameters{Tx: -8, Ty: 160, Tz: 176},
+	}
+
+	datums = []Datum{
+		DatumOSGB36,
+		DatumETRS89,
+		DatumWGS84,
+		DatumTM65,
+		DatumTM75,
+		DatumED50,
+		DatumNAD27,
+	}
+)
+
+// DatumByName returns the registered geodetic datum with a name, ignoring case.
+func DatumByName(name string) (Datum, error) {
+	for _, d := range datums {
+		if strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+	}
+	return Datum{}, fmt.Errorf("%w %q", ErrUnknownDatum, name)
+}
+
+// DatumByEPSG returns the registered geodetic datum whose geographic coordinate
+// reference system has an EPSG code.
+func DatumByEPSG(code int) (Datum, error) {
+	for _, d := range datums {
+		if d.EPSG == code {
+			return d, nil
+		}
+	}
+	return Datum{}, fmt.Errorf("%w EPSG:%d", ErrUnknownDatum, code)
+}
+
+// ConvertTo transforms a position given in decimal degrees and metres of ellipsoidal
+// height from the datum to another, via WGS84 using each datum's Helmert parameters.
+// ErrNonFinite is returned for NaN or infinite positions.
+func (d Datum) ConvertTo(to Datum, lon, lat, height float64) (float64, float64, float64, error) {
+	if !finite(lon) || !finite(lat) || !finite(height) {
+		return 0, 0, 0, ErrNonFinite
+	}
+	geoCoord, err := d.convert(to, &geographicCoord{
+		lat:    degreesToRadians(lat),
+		lon:    degreesToRadians(lon),
+		height: height,
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return radiansToDegrees(geoCoord.lon), radiansToDegrees(geoCoord.lat), geoCoord.height, nil
+}
+
+func (d Datum) convert(to Datum, c *geographicCoord) (*geographicCoord, error) {
+	if d == to {
+		return c, nil
+	}
+	cartCoord := d.Ellipsoid.ellipsoid().geographicToCartesian(c)
+	wgs84Coord := d.ToWGS84.helmert().apply(cartCoord)
+	return to.Ellipsoid.ellipsoid().cartesianToGeographic(to.ToWGS84.helmert().applyInverse(wgs84Coord))
+}
+
+// datumOSGB36 and datumETRS89 are the datums used by the fallback transformation,
+// copied so changes to the exported datums do not affect it.
+var (
+	datumOSGB36 = DatumOSGB36
+	datumETRS89 = DatumETRS89
+)
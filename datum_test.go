@@ -0,0 +1,120 @@
+package osgb
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	for _, e := range ellipsoids {
+		if byName, err := EllipsoidByName(e.Name); err != nil || byName != e {
+			t.Errorf("%s: expected lookup by name, actual %v, %v", e.Name, byName, err)
+		}
+		if byCode, err := EllipsoidByEPSG(e.EPSG); err != nil || byCode != e {
+			t.Errorf("%s: expected lookup by EPSG:%d, actual %v, %v", e.Name, e.EPSG, byCode, err)
+		}
+	}
+	for _, d := range datums {
+		if byName, err := DatumByName(d.Name); err != nil || byName != d {
+			t.Errorf("%s: expected lookup by name, actual %v, %v", d.Name, byName, err)
+		}
+		if byCode, err := DatumByEPSG(d.EPSG); err != nil || byCode != d {
+			t.Errorf("%s: expected lookup by EPSG:%d, actual %v, %v", d.Name, d.EPSG, byCode, err)
+		}
+	}
+	if d, err := DatumByName("osgb36"); err != nil || d != DatumOSGB36 {
+		t.Errorf("expected case insensitive lookup of OSGB36, actual %v, %v", d, err)
+	}
+	if _, err := EllipsoidByEPSG(1); !errors.Is(err, ErrUnknownEllipsoid) {
+		t.Errorf("expected ErrUnknownEllipsoid, actual %v", err)
+	}
+	if _, err := DatumByName("Tokyo"); !errors.Is(err, ErrUnknownDatum) {
+		t.Errorf("expected ErrUnknownDatum, actual %v", err)
+	}
+}
+
+func TestHelmertInverse(t *testing.T) {
+	h := DatumOSGB36.ToWGS84.helmert()
+	c := &cartesianCoord{x: 3874938.850, y: 116218.624, z: 5047168.207}
+	actual := h.applyInverse(h.apply(c))
+	checkDistance(t, "x", c.x, actual.x)
+	checkDistance(t, "y", c.y, actual.y)
+	checkDistance(t, "z", c.z, actual.z)
+}
+
+// Longitudes west of -90 have negative x and y geocentric coordinates.
+func TestDatumNAD27ToWGS84(t *testing.T) {
+	lon, lat, height, err := DatumNAD27.ConvertTo(DatumWGS84, -100, 40, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAngle(t, "lon", -100.0004176, lon)
+	checkAngle(t, "lat", 40.0000095, lat)
+	checkDistance(t, "height", -35.216, height)
+
+	lon, lat, height, err = DatumWGS84.ConvertTo(DatumNAD27, lon, lat, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAngle(t, "lon", -100, lon)
+	checkAngle(t, "lat", 40, lat)
+	checkDistance(t, "height", 0, height)
+}
+
+func TestDatumConvertToNonFinite(t *testing.T) {
+	for _, c := range [][3]float64{
+		{math.NaN(), 52, 0},
+		{-2, math.Inf(-1), 0},
+		{-2, 52, math.NaN()},
+	} {
+		if _, _, _, err := DatumOSGB36.ConvertTo(DatumWGS84, c[0], c[1], c[2]); err != ErrNonFinite {
+			t.Errorf("%v: expected %v, actual %v", c, ErrNonFinite, err)
+		}
+	}
+}
+
+func TestRegistryValues(t *testing.T) {
+	// Changing an exported datum does not change the registry or the transformations using it.
+	saved := DatumOSGB36
+	defer func() { DatumOSGB36 = saved }()
+	DatumOSGB36.ToWGS84.Tx = 0
+	DatumOSGB36.Ellipsoid.SemiMajorAxis = 0
+	if d, err := DatumByEPSG(4277); err != nil || d != saved {
+		t.Errorf("expected registered OSGB36 unchanged, actual %v, %v", d, err)
+	}
+	if datumOSGB36 != saved {
+		t.Errorf("expected OSGB36 used by transformations unchanged, actual %v", datumOSGB36)
+	}
+}
+
+// The Helmert transformation approximates OSTN15 to within a few metres.
+func TestDatumOSGB36ToETRS89(t *testing.T) {
+	inputData, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputData, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pointID, output := range outputData {
+		input := inputData[pointID]
+		lat, lon, err := nationalGridAiry.fromPlaneCoord(&planeCoord{
+			easting:  output.osgb36Easting,
+			northing: output.osgb36Northing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		etrs89Lon, etrs89Lat, _, err := DatumOSGB36.ConvertTo(DatumETRS89, radiansToDegrees(lon), radiansToDegrees(lat), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		north := (etrs89Lat - input.etrs89Lat) * 111200
+		east := (etrs89Lon - input.etrs89Lon) * 111200 * math.Cos(degreesToRadians(input.etrs89Lat))
+		if d := math.Hypot(north, east); d > 6 {
+			t.Errorf("%s: expected Helmert within 6m of OSTN15, actual %fm", pointID, d)
+		}
+	}
+}
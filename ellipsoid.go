@@ -39,15 +39,22 @@ func (el *ellipsoid) geographicToCartesian(c *geographicCoord) *cartesianCoord {
 	}
 }
 
-func (el *ellipsoid) cartesianToGeographic(c *cartesianCoord) *geographicCoord {
-	lon := math.Atan(c.y / c.x)
+// maxGeographicIterations caps the iterations of cartesianToGeographic. Positions
+// near the Earth's surface converge in a handful.
+const maxGeographicIterations = 20
+
+func (el *ellipsoid) cartesianToGeographic(c *cartesianCoord) (*geographicCoord, error) {
+	lon := math.Atan2(c.y, c.x)
 	p := math.Sqrt(c.x*c.x + c.y*c.y)
 	eSq := el.eccentricity()
 	lat := math.Atan(c.z / (p * (1 - eSq)))
 	var v float64
 
 	// Iteratively reach new latitude value
-	for {
+	for iteration := 1; ; iteration++ {
+		if iteration > maxGeographicIterations {
+			return nil, ErrNoConvergence
+		}
 		v = el.semiMajorAxis / math.Sqrt(1.0-eSq*math.Sin(lat)*math.Sin(lat))
 		newLat := math.Atan((c.z + eSq*v*math.Sin(lat)) / p)
 		const epsilon = 0.00000000001
@@ -61,5 +68,5 @@ func (el *ellipsoid) cartesianToGeographic(c *cartesianCoord) *geographicCoord {
 		lat:    lat,
 		lon:    lon,
 		height: height,
-	}
+	}, nil
 }
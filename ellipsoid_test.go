@@ -1,6 +1,7 @@
 package osgb
 
 import (
+	"math"
 	"testing"
 )
 
@@ -42,7 +43,10 @@ func TestCartesianToLatLonHeight(t *testing.T) {
 		z: 5047168.207,
 	}
 
-	geoCoord := airyEllipsoid.cartesianToGeographic(cartesianCoord)
+	geoCoord, err := airyEllipsoid.cartesianToGeographic(cartesianCoord)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	expectedLat, err := dmsToDecimal(52, 39, 27.2531, north)
 	if err != nil {
@@ -60,3 +64,14 @@ func TestCartesianToLatLonHeight(t *testing.T) {
 	checkAngle(t, "longitude", expectedLonRadians, geoCoord.lon)
 	checkDistance(t, "height", expectedHeight, geoCoord.height)
 }
+
+func TestCartesianToGeographicNoConvergence(t *testing.T) {
+	for _, c := range []*cartesianCoord{
+		{x: math.NaN(), y: 116218.624, z: 5047168.207},
+		{x: 3874938.850, y: 116218.624, z: math.NaN()},
+	} {
+		if _, err := airyEllipsoid.cartesianToGeographic(c); err != ErrNoConvergence {
+			t.Errorf("%+v: expected %v, actual %v", *c, ErrNoConvergence, err)
+		}
+	}
+}
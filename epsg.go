@@ -0,0 +1,166 @@
+package osgb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnsupportedCRSError is returned when a pipeline is requested for EPSG codes
+// the package cannot transform.
+type UnsupportedCRSError struct {
+	// Codes are the unsupported EPSG codes
+	Codes []int
+}
+
+func (e *UnsupportedCRSError) Error() string {
+	codes := make([]string, len(e.Codes))
+	for i, code := range e.Codes {
+		codes[i] = fmt.Sprintf("EPSG:%d", code)
+	}
+	return fmt.Sprintf("unsupported coordinate reference systems: %s", strings.Join(codes, ", "))
+}
+
+// ErrEpochRequired is returned for pipelines to or from WGS84. WGS84 follows ITRF,
+// which moves against ETRS89 by around 2.5cm a year, so WGS84 positions can only
+// be related to ETRS89 at their observation epoch. Transform them to ETRS89 with
+// ITRFCoordinate.ToETRS89 first.
+var ErrEpochRequired = errors.New("coordinate reference system needs an observation epoch")
+
+// epochCRSs are the EPSG codes of the WGS84 coordinate reference systems
+// rejected with ErrEpochRequired: geographic 2D and UTM zone 30N.
+var epochCRSs = map[int]bool{4326: true, 32630: true}
+
+// utmZone30GRS80 is the Universal Transverse Mercator projection of zone 30N.
+var utmZone30GRS80 = newProjection(grs80Ellipsoid, 0, degreesToRadians(-3.0), 0.9996, 500000, 0)
+
+// crsSteps returns the steps transforming coordinates in a coordinate reference
+// system to ETRS89 longitude and latitude in radians with ellipsoidal height,
+// the hub all pipelines pass through.
+var crsSteps = map[int]func(tr *GridTransformer) []step{
+	// ETRS89 geographic 2D and 3D, longitude and latitude in degrees
+	4258: func(*GridTransformer) []step { return []step{degreesStep{}} },
+	4937: func(*GridTransformer) []step { return []step{degreesStep{}} },
+	// ETRS89 geocentric Cartesian
+	4936: func(*GridTransformer) []step {
+		return []step{invertedStep{cartesianStep{el: grs80Ellipsoid}}}
+	},
+	// OSGB36 geographic 2D, longitude and latitude in degrees with ODN height
+	4277: func(tr *GridTransformer) []step {
+		return []step{
+			degreesStep{},
+			projectionStep{proj: nationalGridAiry},
+			invertedStep{gridStep{tr: tr}},
+		}
+	},
+	// OSGB36 British National Grid, with ODN height
+	27700: func(tr *GridTransformer) []step { return []step{invertedStep{gridStep{tr: tr}}} },
+	// OSGB36 British National Grid + ODN height
+	7405: func(tr *GridTransformer) []step { return []step{invertedStep{gridStep{tr: tr}}} },
+	// ODN height, at an ETRS89 longitude and latitude in degrees
+	5701: func(tr *GridTransformer) []step {
+		return []step{degreesStep{}, invertedStep{geoidStep{tr: tr}}}
+	},
+	// ETRS89 UTM zone 30N
+	25830: func(*GridTransformer) []step {
+		return []step{invertedStep{projectionStep{proj: utmZone30GRS80}}}
+	},
+}
+
+// embeddedGrid loads an embedded grid transformer once, on first use, to be
+// shared by all pipelines.
+type embeddedGrid struct {
+	once sync.Once
+	load func(opts ...Option) (*GridTransformer, error)
+	tr   *GridTransformer
+	err  error
+}
+
+func (g *embeddedGrid) get() (*GridTransformer, error) {
+	g.once.Do(func() {
+		g.tr, g.err = g.load()
+	})
+	return g.tr, g.err
+}
+
+var (
+	embeddedOSTN02 = &embeddedGrid{load: NewOSTN02Transformer}
+	embeddedOSTN15 = &embeddedGrid{load: NewOSTN15Transformer}
+)
+
+// usesGrid reports whether the steps of a coordinate reference system include
+// a grid or geoid shift.
+func usesGrid(code int) bool {
+	for _, s := range crsSteps[code](nil) {
+		if inverted, ok := s.(invertedStep); ok {
+			s = inverted.step
+		}
+		switch s.(type) {
+		case gridStep, geoidStep:
+			return true
+		}
+	}
+	return false
+}
+
+// Transform returns a pipeline between coordinate reference systems identified by
+// EPSG code, using the OSTN15/OSGM15 transformation where OSGB36 or ODN is involved.
+// The grid is loaded once, by the first pipeline that needs it, and shared by all pipelines.
+//
+// Geographic coordinates are longitude then latitude in decimal degrees, projected
+// coordinates are easting then northing in metres. Heights are ellipsoidal for
+// ETRS89, and ODN for OSGB36, including the 2D OSGB36 systems. EPSG:5701
+// coordinates are an ETRS89 longitude and latitude with an ODN height.
+// WGS84 codes return ErrEpochRequired.
+func Transform(fromEPSG, toEPSG int) (*Pipeline, error) {
+	if err := checkEPSG(fromEPSG, toEPSG); err != nil {
+		return nil, err
+	}
+	var tr *GridTransformer
+	if fromEPSG != toEPSG && (usesGrid(fromEPSG) || usesGrid(toEPSG)) {
+		var err error
+		if tr, err = embeddedOSTN15.get(); err != nil {
+			return nil, err
+		}
+	}
+	return TransformWithGrid(tr, fromEPSG, toEPSG)
+}
+
+// TransformWithGrid returns a pipeline between coordinate reference systems identified
+// by EPSG code, as Transform, using the given grid transformer.
+func TransformWithGrid(tr *GridTransformer, fromEPSG, toEPSG int) (*Pipeline, error) {
+	if err := checkEPSG(fromEPSG, toEPSG); err != nil {
+		return nil, err
+	}
+	p := &Pipeline{}
+	if fromEPSG == toEPSG {
+		return p, nil
+	}
+	p.steps = append(p.steps, crsSteps[fromEPSG](tr)...)
+	toSteps := crsSteps[toEPSG](tr)
+	for i := len(toSteps) - 1; i >= 0; i-- {
+		p.steps = append(p.steps, invertedStep{toSteps[i]})
+	}
+	return p, nil
+}
+
+func checkEPSG(codes ...int) error {
+	for _, code := range codes {
+		if epochCRSs[code] {
+			return fmt.Errorf("%w: EPSG:%d, use ITRFCoordinate.ToETRS89", ErrEpochRequired, code)
+		}
+	}
+	var unsupported []int
+	sort.Ints(codes)
+	for i, code := range codes {
+		if _, ok := crsSteps[code]; !ok && (i == 0 || code != codes[i-1]) {
+			unsupported = append(unsupported, code)
+		}
+	}
+	if len(unsupported) > 0 {
+		return &UnsupportedCRSError{Codes: unsupported}
+	}
+	return nil
+}
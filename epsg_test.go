@@ -0,0 +1,118 @@
+package osgb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransformUnsupported(t *testing.T) {
+	_, err := TransformWithGrid(testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND }), 3857, 2157)
+	var unsupported *UnsupportedCRSError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected UnsupportedCRSError, actual %v", err)
+	}
+	if len(unsupported.Codes) != 2 || unsupported.Codes[0] != 2157 || unsupported.Codes[1] != 3857 {
+		t.Errorf("expected codes [2157 3857], actual %v", unsupported.Codes)
+	}
+	expected := "unsupported coordinate reference systems: EPSG:2157, EPSG:3857"
+	if err.Error() != expected {
+		t.Errorf("expected %q, actual %q", expected, err)
+	}
+}
+
+func TestTransformWGS84(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND })
+	for _, codes := range [][2]int{{4326, 4258}, {27700, 4326}, {32630, 25830}, {4326, 4326}} {
+		if _, err := TransformWithGrid(tr, codes[0], codes[1]); !errors.Is(err, ErrEpochRequired) {
+			t.Errorf("EPSG:%d to EPSG:%d: expected %v, actual %v", codes[0], codes[1], ErrEpochRequired, err)
+		}
+	}
+}
+
+func TestTransformLoadsGridOnlyWhenUsed(t *testing.T) {
+	errLoad := errors.New("grid loaded")
+	saved := embeddedOSTN15
+	defer func() { embeddedOSTN15 = saved }()
+	embeddedOSTN15 = &embeddedGrid{load: func(...Option) (*GridTransformer, error) {
+		return nil, errLoad
+	}}
+
+	for _, codes := range [][2]int{{4258, 25830}, {4937, 4258}, {4936, 25830}, {27700, 27700}} {
+		if _, err := Transform(codes[0], codes[1]); err != nil {
+			t.Errorf("EPSG:%d to EPSG:%d: unexpected error %v", codes[0], codes[1], err)
+		}
+	}
+	for _, codes := range [][2]int{{4258, 27700}, {7405, 4936}, {4277, 4258}, {4937, 5701}} {
+		if _, err := Transform(codes[0], codes[1]); err != errLoad {
+			t.Errorf("EPSG:%d to EPSG:%d: expected grid to be loaded, actual error %v", codes[0], codes[1], err)
+		}
+	}
+}
+
+func TestTransformRoundTrip(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND })
+	const lon, lat, height = -2.5, 53.5, 100.0
+	for _, code := range []int{4258, 4937, 4936, 4277, 27700, 7405, 5701, 25830} {
+		p, err := TransformWithGrid(tr, 4937, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y, z, err := p.Forward(lon, lat, height)
+		if err != nil {
+			t.Errorf("EPSG:%d: %s", code, err)
+			continue
+		}
+		back, err := TransformWithGrid(tr, code, 4937)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actualLon, actualLat, actualHeight, err := back.Forward(x, y, z)
+		if err != nil {
+			t.Errorf("EPSG:%d: %s", code, err)
+			continue
+		}
+		checkAngle(t, "Lon", lon, actualLon)
+		checkAngle(t, "Lat", lat, actualLat)
+		checkDistance(t, "Height", height, actualHeight)
+	}
+}
+
+func TestTransformUTM(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND })
+	p, err := TransformWithGrid(tr, 4258, 25830)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// On the central meridian the easting is the false easting
+	easting, _, _, err := p.Forward(-3, 53.5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "Easting", 500000, easting)
+}
+
+func TestTransform15Data(t *testing.T) {
+	inputs, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Transform(4937, 7405)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pointID, input := range inputs {
+		output := outputs[pointID]
+		easting, northing, height, err := p.Forward(input.etrs89Lon, input.etrs89Lat, input.etrs89Height)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+		checkDistance(t, "osgb36 east", output.osgb36Easting, easting)
+		checkDistance(t, "osgb36 north", output.osgb36Northing, northing)
+		checkDistance(t, "odn height", output.odnHeight, height)
+	}
+}
@@ -0,0 +1,64 @@
+package osgb
+
+import "math"
+
+// Positions the grid cannot transform fall back to the OS 7 parameter Helmert
+// transformation between ETRS89 and OSGB36 under OffshoreFallback. OS quote it as
+// matching the National Grid to around 5m, so it is only suitable for positions
+// with no grid coverage at all.
+//
+// The Helmert transformation relates ellipsoidal heights, and without the geoid
+// there is no way between them and ODN heights, so fallback results have NaN
+// heights. Input heights are ignored: the horizontal position changes by only a
+// few centimetres for a kilometre of height, well within the fallback's accuracy.
+
+// fallsBack reports whether a grid transformation error at a horizontal position
+// should be retried with the Helmert transformation. Non-finite positions are
+// never retried, and keep the grid error.
+func (tr *GridTransformer) fallsBack(err error, x, y float64) bool {
+	return tr.offshorePolicy == OffshoreFallback &&
+		(err == ErrPointOffshore || err == ErrPointOutsidePolygon || err == ErrPointOutsideTransformation) &&
+		finite(x) && finite(y)
+}
+
+// helmertToNationalGrid transforms an ETRS89 position to the National Grid with the
+// Helmert transformation. The height of the result is NaN.
+func helmertToNationalGrid(c *ETRS89Coordinate) (OSGB36Coordinate, error) {
+	osgb36Coord, err := datumETRS89.convert(datumOSGB36, &geographicCoord{
+		lat: degreesToRadians(c.Lat),
+		lon: degreesToRadians(c.Lon),
+	})
+	if err != nil {
+		return OSGB36Coordinate{}, err
+	}
+	coord := nationalGridAiry.toPlaneCoord(osgb36Coord.lat, osgb36Coord.lon)
+	return OSGB36Coordinate{
+		Easting:  coord.easting,
+		Northing: coord.northing,
+		Height:   math.NaN(),
+	}, nil
+}
+
+// helmertFromNationalGrid transforms a National Grid position to ETRS89 with the
+// Helmert transformation. The height of the result is NaN.
+func helmertFromNationalGrid(c *OSGB36Coordinate) (ETRS89Coordinate, error) {
+	lat, lon, err := nationalGridAiry.fromPlaneCoord(&planeCoord{
+		easting:  c.Easting,
+		northing: c.Northing,
+	})
+	if err != nil {
+		return ETRS89Coordinate{}, err
+	}
+	etrs89Coord, err := datumOSGB36.convert(datumETRS89, &geographicCoord{
+		lat: lat,
+		lon: lon,
+	})
+	if err != nil {
+		return ETRS89Coordinate{}, err
+	}
+	return ETRS89Coordinate{
+		Lon:    radiansToDegrees(etrs89Coord.lon),
+		Lat:    radiansToDegrees(etrs89Coord.lat),
+		Height: math.NaN(),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package osgb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOffshoreFallback(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		if e >= 400 {
+			return Region_OFFSHORE
+		}
+		return Region_UK_MAINLAND
+	})
+	tr.offshorePolicy = OffshoreFallback
+
+	onshore := ETRS89Coordinate{Lon: -3, Lat: 52.5, Height: 100}
+	osgb36Coord, err := tr.ToNationalGridValue(onshore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osgb36Coord.VerticalDatum != VerticalDatumNewlyn {
+		t.Errorf("expected onshore position transformed with the grid, actual %+v", osgb36Coord)
+	}
+
+	for _, c := range []ETRS89Coordinate{
+		{Lon: 1.5, Lat: 52.5, Height: 20}, // offshore grid cell
+		{Lon: -2, Lat: 70, Height: 20},    // beyond the grid
+	} {
+		expected, err := helmertToNationalGrid(&c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := tr.ToNationalGridValue(c)
+		if err != nil {
+			t.Fatalf("%+v: %v", c, err)
+		}
+		if actual.Easting != expected.Easting || actual.Northing != expected.Northing ||
+			!math.IsNaN(actual.Height) || actual.VerticalDatum != (VerticalDatum{}) {
+			t.Errorf("%+v: expected Helmert result %+v with a NaN height, actual %+v", c, expected, actual)
+		}
+		// An ODN height given to the fallback is not mistaken for an ellipsoidal height.
+		actual.Height = 50
+		etrs89Coord, err := tr.FromNationalGridValue(actual)
+		if err != nil {
+			t.Fatalf("%+v: %v", actual, err)
+		}
+		checkAngle(t, "Lon", c.Lon, etrs89Coord.Lon)
+		checkAngle(t, "Lat", c.Lat, etrs89Coord.Lat)
+		if !math.IsNaN(etrs89Coord.Height) {
+			t.Errorf("%+v: expected NaN height, actual %f", actual, etrs89Coord.Height)
+		}
+
+		eastings, northings, heights := []float64{c.Lon}, []float64{c.Lat}, []float64{c.Height}
+		if err := tr.ToNationalGridBatch(eastings, northings, heights, eastings, northings, heights); err != nil {
+			t.Fatal(err)
+		}
+		checkDistance(t, "batch Easting", expected.Easting, eastings[0])
+		checkDistance(t, "batch Northing", expected.Northing, northings[0])
+		if !math.IsNaN(heights[0]) {
+			t.Errorf("%+v: expected NaN batch height, actual %f", c, heights[0])
+		}
+	}
+
+	tr.offshorePolicy = OffshoreReject
+	if _, err := tr.ToNationalGridValue(ETRS89Coordinate{Lon: 1.5, Lat: 52.5}); err != ErrPointOffshore {
+		t.Errorf("expected %v, actual %v", ErrPointOffshore, err)
+	}
+}
+
+func TestOffshoreFallbackNonFinite(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND })
+	tr.offshorePolicy = OffshoreFallback
+
+	nan, inf := math.NaN(), math.Inf(1)
+	for _, c := range []ETRS89Coordinate{
+		{Lon: nan, Lat: 52},
+		{Lon: -2, Lat: nan},
+		{Lon: inf, Lat: 52},
+	} {
+		if _, err := tr.ToNationalGridValue(c); err == nil {
+			t.Errorf("%+v: expected the grid error, not a fallback result", c)
+		}
+		eastings, northings, heights := []float64{c.Lon}, []float64{c.Lat}, []float64{c.Height}
+		if err := tr.ToNationalGridBatch(eastings, northings, heights, eastings, northings, heights); err != nil {
+			t.Fatal(err)
+		}
+		if !math.IsNaN(eastings[0]) || !math.IsNaN(northings[0]) || !math.IsNaN(heights[0]) {
+			t.Errorf("%+v: expected NaN batch result, actual (%f, %f, %f)", c, eastings[0], northings[0], heights[0])
+		}
+	}
+	for _, c := range []OSGB36Coordinate{
+		{Easting: nan, Northing: 300000},
+		{Easting: 400000, Northing: inf},
+	} {
+		if _, err := tr.FromNationalGridValue(c); err == nil {
+			t.Errorf("%+v: expected the grid error, not a fallback result", c)
+		}
+	}
+}
+
+func TestHelmertToNationalGrid(t *testing.T) {
+	// The OSTN15 result for OS test point TP31 near St Kilda is (9587.909, 899448.996).
+	// The Helmert transformation agrees to within a few metres.
+	c := &ETRS89Coordinate{Lat: 57.81351838410, Lon: -8.57854456076, Height: 100.001}
+	osgb36Coord, err := helmertToNationalGrid(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := math.Hypot(osgb36Coord.Easting-9587.909, osgb36Coord.Northing-899448.996); d > 5 {
+		t.Errorf("expected Helmert result within 5m of OSTN15, actual %+v, %.1fm away", osgb36Coord, d)
+	}
+}
@@ -0,0 +1,381 @@
+package osgb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
+)
+
+// The binary grid file holds the records of a rectangular block of the 1km
+// grid, so it can be memory mapped and read in place by several processes.
+// All values are little endian. The 128 byte header is:
+//
+//	magic        [8]byte  "OSGBGRID"
+//	version      uint32   2
+//	origin       2*uint32 ETRS89 easting and northing of the south west node, in metres
+//	size         2*uint32 number of nodes east and north
+//	spacing      uint32   1000, the node spacing in metres
+//	model        [16]byte OS model the grid was built from, e.g. "OSTN/OSGM", NUL padded
+//	version      [8]byte  model version, e.g. "15", NUL padded
+//	release date [8]byte  model release date, e.g. "2016-08", NUL padded
+//	source sum   [32]byte SHA-256 digest of the translation vector file the grid
+//	                      was built from, or zero if there was none
+//	records sum  [32]byte SHA-256 digest of the records
+//
+// The model fields are empty for custom grids. The header is followed by a 16 byte record per node, row by row from the south west:
+//
+//	east shift   int32    millimetres
+//	north shift  int32    millimetres
+//	geoid height int32    millimetres
+//	datum flag   uint8    the geoid region
+//	padding      [3]byte
+const (
+	gridFileMagic      = "OSGBGRID"
+	gridFileVersion    = 2
+	gridFileHeaderSize = 128
+	gridFileRecordSize = 16
+	gridSpacing        = 1000
+)
+
+var errGridFile = errors.New("invalid grid file")
+
+// ErrGridClosed indicates a MappedTransformer was used after it was closed.
+var ErrGridClosed = errors.New("grid file closed")
+
+// MappedTransformer is a GridTransformer reading its grid from a file. Close
+// releases the file, after which transformations return ErrGridClosed.
+type MappedTransformer struct {
+	*GridTransformer
+}
+
+// NewMappedTransformer returns a transformer on a binary grid file written by
+// WriteBinaryGrid. Where the platform supports it the file is memory mapped, so
+// processes on the same host share one copy of the grid in the page cache, and
+// otherwise it is read into memory. WithGrid cannot be used with a grid file.
+func NewMappedTransformer(path string, opts ...Option) (*MappedTransformer, error) {
+	grid, err := openGridFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := configureTransformer(opts)
+	if err == nil && tr.records != nil {
+		err = errors.New("WithGrid cannot be used with a grid file")
+	}
+	if err != nil {
+		grid.close()
+		return nil, err
+	}
+	tr.mapped = grid
+	tr.model = releaseModel(grid.model)
+	if err := tr.checkExpectedChecksum(); err != nil {
+		grid.close()
+		return nil, err
+	}
+	return &MappedTransformer{tr}, nil
+}
+
+// Close releases the grid file. Transformations already reading the grid keep
+// it open until they finish, and an error releasing it then is not reported.
+func (tr *MappedTransformer) Close() error {
+	return tr.mapped.close()
+}
+
+// mappedGrid is a block of the grid read from a binary grid file. Records
+// outside the block are outside the transformation.
+type mappedGrid struct {
+	// file is the whole grid file, and data its records
+	file []byte
+	data []byte
+	// eastIndex and northIndex are the grid indices of the south west node
+	eastIndex, northIndex uint32
+	nEast, nNorth         uint32
+	unmap                 func() error
+	// model is the OS model recorded in the header, sourceSum the hex encoded
+	// digest of its translation vector file and recordsSum the digest of data
+	model      gridModel
+	sourceSum  string
+	recordsSum [sha256.Size]byte
+	// refs counts the transformations reading the grid in steps of two, with the
+	// lowest bit set once the grid is closed. The data is unmapped when the grid
+	// is closed and no transformation is reading it.
+	refs atomic.Int64
+}
+
+const gridClosed = 1
+
+func openGridFile(path string) (*mappedGrid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < gridFileHeaderSize || info.Size() > math.MaxInt32 {
+		return nil, fmt.Errorf("%s: unexpected file size %d", errGridFile, info.Size())
+	}
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	grid, err := parseGridFile(data)
+	if err != nil {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, err
+	}
+	grid.unmap = unmap
+	return grid, nil
+}
+
+// parseGridFile validates the header and records of a binary grid file.
+func parseGridFile(data []byte) (*mappedGrid, error) {
+	if len(data) < gridFileHeaderSize || string(data[:8]) != gridFileMagic {
+		return nil, fmt.Errorf("%s: missing %s header", errGridFile, gridFileMagic)
+	}
+	header := make([]uint32, 6)
+	for i := range header {
+		header[i] = binary.LittleEndian.Uint32(data[8+4*i:])
+	}
+	version, originEast, originNorth, nEast, nNorth, spacing := header[0], header[1], header[2], header[3], header[4], header[5]
+	if version != gridFileVersion {
+		return nil, fmt.Errorf("%s: unsupported version %d", errGridFile, version)
+	}
+	if spacing != gridSpacing || originEast%gridSpacing != 0 || originNorth%gridSpacing != 0 {
+		return nil, fmt.Errorf("%s: origin (%d, %d) and spacing %d do not match the 1km grid",
+			errGridFile, originEast, originNorth, spacing)
+	}
+	grid := &mappedGrid{
+		eastIndex:  originEast / gridSpacing,
+		northIndex: originNorth / gridSpacing,
+		nEast:      nEast,
+		nNorth:     nNorth,
+	}
+	if nEast < 2 || nNorth < 2 || uint64(grid.eastIndex)+uint64(nEast) > nEastIndices ||
+		uint64(grid.northIndex)+uint64(nNorth) > nNorthIndices {
+		return nil, fmt.Errorf("%s: %dx%d nodes from (%d, %d) do not fit in the %dx%d grid",
+			errGridFile, nEast, nNorth, originEast, originNorth, nEastIndices, nNorthIndices)
+	}
+	if expected := gridFileHeaderSize + int(nEast)*int(nNorth)*gridFileRecordSize; len(data) != expected {
+		return nil, fmt.Errorf("%s: file has %d bytes, expected %d", errGridFile, len(data), expected)
+	}
+	grid.model = gridModel{
+		name:        headerString(data[32:48]),
+		version:     headerString(data[48:56]),
+		releaseDate: headerString(data[56:64]),
+	}
+	if sourceSum := data[64:96]; !bytes.Equal(sourceSum, make([]byte, sha256.Size)) {
+		grid.sourceSum = hex.EncodeToString(sourceSum)
+	}
+	copy(grid.recordsSum[:], data[96:128])
+	grid.file = data
+	grid.data = data[gridFileHeaderSize:]
+	for i := 0; i < len(grid.data); i += gridFileRecordSize {
+		if _, err := geoidDatumToRegion(uint64(grid.data[i+12])); err != nil {
+			return nil, fmt.Errorf("%s: record %d: %s", errGridFile, i/gridFileRecordSize+1, err)
+		}
+	}
+	return grid, nil
+}
+
+// headerString returns a NUL padded string field of the header.
+func headerString(b []byte) string {
+	return string(bytes.TrimRight(b, "\x00"))
+}
+
+// record returns the record at an index of the grid. The caller must hold a
+// reference to the grid.
+func (g *mappedGrid) record(index uint32) record {
+	e, n := index%nEastIndices, index/nEastIndices
+	rec := record{
+		recordNo:       index + 1,
+		etrs89Easting:  e * gridSpacing,
+		etrs89Northing: n * gridSpacing,
+	}
+	// Indices below the origin wrap around to large values
+	e, n = e-g.eastIndex, n-g.northIndex
+	if e >= g.nEast || n >= g.nNorth {
+		rec.geoidRegion = Region_OUTSIDE_TRANSFORMATION
+		return rec
+	}
+	b := g.data[(e+n*g.nEast)*gridFileRecordSize:]
+	b = b[:gridFileRecordSize]
+	rec.ostnEastShift = float64(int32(binary.LittleEndian.Uint32(b[0:]))) / 1000
+	rec.ostnNorthShift = float64(int32(binary.LittleEndian.Uint32(b[4:]))) / 1000
+	rec.ostnGeoidHeight = float64(int32(binary.LittleEndian.Uint32(b[8:]))) / 1000
+	rec.geoidRegion = GeoidRegion(b[12])
+	return rec
+}
+
+// checksum computes the checksum of the grid file as it is now, as a shared
+// mapping reflects changes made to the file after it was opened.
+func (g *mappedGrid) checksum() (string, error) {
+	if !g.acquire() {
+		return "", ErrGridClosed
+	}
+	defer g.release()
+	sum := sha256.Sum256(g.file)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verifyRecords checks the records still match the digest in the header.
+func (g *mappedGrid) verifyRecords() error {
+	if !g.acquire() {
+		return ErrGridClosed
+	}
+	defer g.release()
+	if sha256.Sum256(g.data) != g.recordsSum {
+		return fmt.Errorf("%w: records changed since the grid file was written", ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// acquire takes a reference to the grid, or returns false if it is closed.
+func (g *mappedGrid) acquire() bool {
+	for {
+		refs := g.refs.Load()
+		if refs&gridClosed != 0 {
+			return false
+		}
+		if g.refs.CompareAndSwap(refs, refs+2) {
+			return true
+		}
+	}
+}
+
+// release drops a reference to the grid, unmapping it if the grid is closed
+// and this was the last reference.
+func (g *mappedGrid) release() {
+	if g.refs.Add(-2) == gridClosed {
+		g.unmapData()
+	}
+}
+
+// close stops new references being taken to the grid, and unmaps it unless
+// transformations are still reading it, in which case the last to finish does.
+func (g *mappedGrid) close() error {
+	for {
+		refs := g.refs.Load()
+		if refs&gridClosed != 0 {
+			return nil
+		}
+		if g.refs.CompareAndSwap(refs, refs|gridClosed) {
+			if refs != 0 {
+				return nil
+			}
+			return g.unmapData()
+		}
+	}
+}
+
+func (g *mappedGrid) unmapData() error {
+	g.file, g.data = nil, nil
+	if g.unmap == nil {
+		return nil
+	}
+	return g.unmap()
+}
+
+// acquireGrid takes a reference to a mapped grid for the duration of a
+// transformation, so Close does not unmap it while its records are read. It
+// returns ErrGridClosed if the grid is closed.
+func (tr *GridTransformer) acquireGrid() error {
+	if tr.mapped != nil && !tr.mapped.acquire() {
+		return ErrGridClosed
+	}
+	return nil
+}
+
+// releaseGrid drops a reference taken by acquireGrid.
+func (tr *GridTransformer) releaseGrid() {
+	if tr.mapped != nil {
+		tr.mapped.release()
+	}
+}
+
+// WriteBinaryGrid writes the transformation grid as a binary grid file for
+// NewMappedTransformer. Shifts and heights are stored to the millimetre,
+// the precision of the published grid. The file records the OS model and
+// the checksum of the translation vector file the grid was built from, which
+// a MappedTransformer reports in its Metadata and checks in Verify.
+func (tr *GridTransformer) WriteBinaryGrid(w io.Writer) error {
+	return tr.writeGridFile(w, 0, 0, nEastIndices, nNorthIndices)
+}
+
+// writeGridFile writes a block of nEast by nNorth grid nodes, with its south west
+// node at the given indices, as a binary grid file.
+func (tr *GridTransformer) writeGridFile(w io.Writer, eastIndex, northIndex, nEast, nNorth uint32) error {
+	if err := tr.acquireGrid(); err != nil {
+		return err
+	}
+	defer tr.releaseGrid()
+	sourceSum, err := tr.sourceChecksum()
+	if err != nil {
+		return err
+	}
+	source, err := hex.DecodeString(sourceSum)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, gridFileHeaderSize)
+	copy(header, gridFileMagic)
+	for i, v := range []uint32{gridFileVersion, eastIndex * gridSpacing, northIndex * gridSpacing, nEast, nNorth, gridSpacing} {
+		binary.LittleEndian.PutUint32(header[8+4*i:], v)
+	}
+	if tr.model != nil {
+		copy(header[32:48], tr.model.name)
+		copy(header[48:56], tr.model.version)
+		copy(header[56:64], tr.model.releaseDate)
+	}
+	copy(header[64:96], source)
+	// The records are encoded twice, to hash them for the header and then to write them.
+	records := sha256.New()
+	tr.encodeGridRecords(eastIndex, northIndex, nEast, nNorth, func(b []byte) error {
+		records.Write(b)
+		return nil
+	})
+	copy(header[96:128], records.Sum(nil))
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	err = tr.encodeGridRecords(eastIndex, northIndex, nEast, nNorth, func(b []byte) error {
+		_, err := bw.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// encodeGridRecords passes the binary grid file record of each node of a block to write in turn.
+func (tr *GridTransformer) encodeGridRecords(eastIndex, northIndex, nEast, nNorth uint32, write func([]byte) error) error {
+	buf := make([]byte, gridFileRecordSize)
+	for n := northIndex; n < northIndex+nNorth; n++ {
+		for e := eastIndex; e < eastIndex+nEast; e++ {
+			rec := tr.record(e + n*nEastIndices)
+			for i, v := range []float64{rec.ostnEastShift, rec.ostnNorthShift, rec.ostnGeoidHeight} {
+				binary.LittleEndian.PutUint32(buf[4*i:], uint32(int32(math.Round(v*1000))))
+			}
+			buf[12] = byte(rec.geoidRegion)
+			if err := write(buf); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
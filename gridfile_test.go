@@ -0,0 +1,242 @@
+package osgb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeTestGridFile writes a transformer's grid to a binary grid file in a
+// temporary directory.
+func writeTestGridFile(t *testing.T, tr *GridTransformer) string {
+	var buf bytes.Buffer
+	if err := tr.WriteBinaryGrid(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return writeTestFile(t, buf.Bytes())
+}
+
+func writeTestFile(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "grid.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMappedTransformer(t *testing.T) {
+	tr := syntheticTransformer()
+	mapped, err := NewMappedTransformer(writeTestGridFile(t, tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range track(-4, 52, 200) {
+		expected, err := tr.ToNationalGridValue(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := mapped.ToNationalGridValue(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDistance(t, "Easting", expected.Easting, actual.Easting)
+		checkDistance(t, "Northing", expected.Northing, actual.Northing)
+		checkDistance(t, "Height", expected.Height, actual.Height)
+		etrs89Coord, err := mapped.FromNationalGridValue(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkAngle(t, "Lon", c.Lon, etrs89Coord.Lon)
+		checkAngle(t, "Lat", c.Lat, etrs89Coord.Lat)
+	}
+	if _, err := mapped.ToNationalGridValue(ETRS89Coordinate{Lon: -2, Lat: 70}); err != ErrPointOutsidePolygon {
+		t.Errorf("expected %v, actual %v", ErrPointOutsidePolygon, err)
+	}
+	if err := mapped.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := mapped.Close(); err != nil {
+		t.Errorf("expected second close to succeed, actual %v", err)
+	}
+}
+
+func TestMappedTransformerClose(t *testing.T) {
+	tr := syntheticTransformer()
+	path := writeTestGridFile(t, tr)
+	c := ETRS89Coordinate{Lon: -2, Lat: 52.5}
+	for _, opts := range [][]Option{nil, {WithCellCache()}, {WithInverseGrid()},
+		{WithInterpolation(InterpolationBicubic)}, {WithInterpolation(InterpolationBiquadratic)}} {
+		mapped, err := NewMappedTransformer(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		osgb36Coord, err := mapped.ToNationalGridValue(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mapped.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mapped.ToNationalGridValue(c); err != ErrGridClosed {
+			t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+		}
+		if _, err := mapped.FromNationalGridValue(osgb36Coord); err != ErrGridClosed {
+			t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+		}
+		if _, _, err := mapped.ToNationalGridWithAccuracy(&c); err != ErrGridClosed {
+			t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+		}
+		if err := mapped.WriteBinaryGrid(io.Discard); err != ErrGridClosed {
+			t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+		}
+		if status := mapped.Covers(&c); status != CoverageUnknown {
+			t.Errorf("expected %s, actual %s", CoverageUnknown, status)
+		}
+		if status := mapped.CoversNationalGrid(&osgb36Coord); status != CoverageUnknown {
+			t.Errorf("expected %s, actual %s", CoverageUnknown, status)
+		}
+		if _, err := mapped.Shifts(400000, 300000); err != ErrGridClosed {
+			t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+		}
+		if _, err := mapped.Coverage(); err != ErrGridClosed {
+			t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+		}
+	}
+}
+
+func TestMappedTransformerCloseInUse(t *testing.T) {
+	tr := syntheticTransformer()
+	mapped, err := NewMappedTransformer(writeTestGridFile(t, tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mapped.acquireGrid(); err != nil {
+		t.Fatal(err)
+	}
+	index := uint32(400 + 300*nEastIndices)
+	expected := mapped.record(index)
+	if err := mapped.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mapped.acquireGrid(); err != ErrGridClosed {
+		t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+	}
+	// The grid stays mapped until the transformation reading it finishes.
+	if actual := mapped.record(index); actual != expected {
+		t.Errorf("expected %+v, actual %+v", expected, actual)
+	}
+	mapped.releaseGrid()
+	if mapped.mapped.data != nil {
+		t.Error("expected the grid to be released")
+	}
+}
+
+func TestMappedTransformerConcurrentClose(t *testing.T) {
+	tr := syntheticTransformer()
+	mapped, err := NewMappedTransformer(writeTestGridFile(t, tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, c := range track(-4, 52, 2000) {
+				if _, err := mapped.ToNationalGridValue(c); err != nil && err != ErrGridClosed {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	if err := mapped.Close(); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+}
+
+func TestMappedTransformerOptions(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		if e >= 400 {
+			return Region_OFFSHORE
+		}
+		return Region_UK_MAINLAND
+	})
+	path := writeTestGridFile(t, tr)
+	mapped, err := NewMappedTransformer(path, WithStrictOnshore(), WithCellCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	if _, err := mapped.ToNationalGridValue(ETRS89Coordinate{Lon: 1.5, Lat: 52.5}); err != ErrPointOffshore {
+		t.Errorf("expected %v, actual %v", ErrPointOffshore, err)
+	}
+
+	if _, err := NewMappedTransformer(path, WithGrid(strings.NewReader(""))); err == nil {
+		t.Error("expected WithGrid to be rejected")
+	}
+}
+
+func TestInvalidGridFile(t *testing.T) {
+	tr := syntheticTransformer()
+	var buf bytes.Buffer
+	if err := tr.writeGridFile(&buf, 100, 200, 20, 10); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+	corrupt := func(offset int, b byte) []byte {
+		data := append([]byte(nil), valid...)
+		data[offset] = b
+		return data
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"magic":     corrupt(0, 'X'),
+		"version":   corrupt(8, 1),
+		"spacing":   corrupt(28, 0),
+		"origin":    corrupt(12, 1),
+		"extent":    corrupt(23, 1),
+		"truncated": valid[:len(valid)-1],
+		"region":    corrupt(gridFileHeaderSize+5*gridFileRecordSize+12, 17),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewMappedTransformer(writeTestFile(t, data)); err == nil {
+				t.Error("expected invalid grid file to be rejected")
+			}
+		})
+	}
+	if _, err := NewMappedTransformer(filepath.Join(t.TempDir(), "missing.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected missing file error, actual %v", err)
+	}
+}
+
+func TestGridFileBlock(t *testing.T) {
+	tr := syntheticTransformer()
+	var buf bytes.Buffer
+	if err := tr.writeGridFile(&buf, 100, 200, 20, 10); err != nil {
+		t.Fatal(err)
+	}
+	grid, err := parseGridFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []uint32{100 + 200*nEastIndices, 119 + 209*nEastIndices, 110 + 205*nEastIndices} {
+		expected := tr.record(index)
+		actual := grid.record(index)
+		if actual.recordNo != expected.recordNo || actual.geoidRegion != expected.geoidRegion ||
+			actual.etrs89Easting != expected.etrs89Easting || actual.etrs89Northing != expected.etrs89Northing {
+			t.Errorf("record %d: expected %+v, actual %+v", index, expected, actual)
+		}
+	}
+	for _, index := range []uint32{99 + 200*nEastIndices, 120 + 205*nEastIndices, 110 + 199*nEastIndices, 110 + 210*nEastIndices} {
+		if rec := grid.record(index); rec.geoidRegion != Region_OUTSIDE_TRANSFORMATION {
+			t.Errorf("record %d: expected outside the block, actual %v", index, rec.geoidRegion)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package osgb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mjjbell/go-osgb/internal/data"
+)
+
+var (
+	// ErrChecksumMismatch indicates the grid does not match its expected checksum.
+	ErrChecksumMismatch = errors.New("grid checksum mismatch")
+	// ErrNoChecksum indicates there is no expected checksum to verify the grid against.
+	ErrNoChecksum = errors.New("no expected grid checksum")
+)
+
+// GridMetadata describes the transformation grid used by a transformer.
+type GridMetadata struct {
+	// Model and Version identify the OS release, e.g. OSTN/OSGM version 15.
+	// Both are empty for custom grids.
+	Model   string
+	Version string
+	// ReleaseDate is when Ordnance Survey released the grid, to the precision
+	// it is published, e.g. "2016-08".
+	ReleaseDate string
+	// Records is the number of grid nodes held.
+	Records int
+	// Extent is the ETRS89 grid positions of the south west and north east
+	// nodes held, in metres.
+	MinEasting, MinNorthing, MaxEasting, MaxNorthing float64
+	// Checksum is the hex encoded SHA-256 digest of the file the grid was read
+	// from, so it can be checked with standard tools such as sha256sum. For the
+	// built-in grids and grids loaded with WithGrid it is the digest of the OS
+	// translation vector file, such as OSTN15_OSGM15_DataFile.txt as published,
+	// and for a MappedTransformer the digest of the binary grid file. It is empty
+	// for grids with no source file.
+	Checksum string
+	// SourceChecksum is the hex encoded SHA-256 digest of the OS translation
+	// vector file the grid was built from. It is Checksum except for a
+	// MappedTransformer, which reports the digest recorded in the binary grid
+	// file when it was written.
+	SourceChecksum string
+}
+
+// gridModel identifies an OS grid release.
+type gridModel struct {
+	name, version, releaseDate string
+	// checksum is the SHA-256 digest of the translation vector file published by
+	// OS for the release, which the embedded data must match. It is empty where
+	// none has been recorded.
+	checksum string
+}
+
+// The digests of the published OSTN02_OSGM02_GB.txt and OSTN15_OSGM15_DataFile.txt
+// are still to be recorded from the OS downloads. Until they are, Verify returns
+// ErrNoChecksum for the built-in grids, and Test02Verify and Test15Verify are skipped.
+var (
+	ostn02Model = &gridModel{name: "OSTN/OSGM", version: "02", releaseDate: "2002"}
+	ostn15Model = &gridModel{name: "OSTN/OSGM", version: "15", releaseDate: "2016-08"}
+)
+
+// releaseModel returns the OS release matching a model recorded in a binary
+// grid file, a model with no recorded checksum if it is not a known release, or
+// nil for a custom grid.
+func releaseModel(model gridModel) *gridModel {
+	for _, release := range []*gridModel{ostn02Model, ostn15Model} {
+		if model.name == release.name && model.version == release.version && model.releaseDate == release.releaseDate {
+			return release
+		}
+	}
+	if model == (gridModel{}) {
+		return nil
+	}
+	return &model
+}
+
+// gridChecksum is the lazily computed checksum of a transformer's grid, as
+// reported by Metadata. Verify computes the checksum again rather than using it.
+type gridChecksum struct {
+	once sync.Once
+	sum  string
+}
+
+// Metadata describes the transformation grid, including the checksum of the
+// file it was read from.
+func (tr *GridTransformer) Metadata() GridMetadata {
+	eastIndex, northIndex, nEast, nNorth := tr.gridBlock()
+	md := GridMetadata{
+		Records:     int(nEast * nNorth),
+		MinEasting:  float64(eastIndex * gridSpacing),
+		MinNorthing: float64(northIndex * gridSpacing),
+		MaxEasting:  float64((eastIndex + nEast - 1) * gridSpacing),
+		MaxNorthing: float64((northIndex + nNorth - 1) * gridSpacing),
+		Checksum:    tr.metadataChecksum(),
+	}
+	md.SourceChecksum = md.Checksum
+	if tr.mapped != nil {
+		md.SourceChecksum = tr.mapped.sourceSum
+	}
+	if tr.model != nil {
+		md.Model, md.Version, md.ReleaseDate = tr.model.name, tr.model.version, tr.model.releaseDate
+	}
+	return md
+}
+
+// Verify computes the checksum of the file the grid is read from again, and checks
+// it against the checksum given with WithChecksum. Without one, it checks the
+// source checksum against the recorded checksum of the official release; the
+// records of a MappedTransformer are first checked against the digest written
+// with them. It returns ErrChecksumMismatch if they differ, and ErrNoChecksum if
+// there is no checksum to compare with.
+func (tr *GridTransformer) Verify() error {
+	if tr.expectedChecksum != "" {
+		sum, err := tr.checksum()
+		if err != nil {
+			return err
+		}
+		return compareChecksum(tr.expectedChecksum, sum)
+	}
+	if tr.mapped != nil {
+		if err := tr.mapped.verifyRecords(); err != nil {
+			return err
+		}
+	}
+	if tr.model == nil || tr.model.checksum == "" {
+		return ErrNoChecksum
+	}
+	sum, err := tr.sourceChecksum()
+	if err != nil {
+		return err
+	}
+	return compareChecksum(tr.model.checksum, sum)
+}
+
+func compareChecksum(expected, sum string) error {
+	if sum != expected {
+		return fmt.Errorf("%w: expected %s, actual %s", ErrChecksumMismatch, expected, sum)
+	}
+	return nil
+}
+
+// gridBlock returns the indices of the south west node and the size of the
+// block of grid nodes the transformer holds.
+func (tr *GridTransformer) gridBlock() (uint32, uint32, uint32, uint32) {
+	if tr.mapped != nil {
+		return tr.mapped.eastIndex, tr.mapped.northIndex, tr.mapped.nEast, tr.mapped.nNorth
+	}
+	return 0, 0, nEastIndices, nNorthIndices
+}
+
+// metadataChecksum returns the checksum of the grid's file, computing it on first use.
+func (tr *GridTransformer) metadataChecksum() string {
+	tr.sum.once.Do(func() {
+		// A grid file closed before its checksum is first reported has none
+		tr.sum.sum, _ = tr.checksum()
+	})
+	return tr.sum.sum
+}
+
+// checksum computes the checksum of the file the grid was read from: the embedded
+// translation vector file, the file read with WithGrid, or the binary grid file.
+func (tr *GridTransformer) checksum() (string, error) {
+	switch {
+	case tr.mapped != nil:
+		return tr.mapped.checksum()
+	case tr.asset != "":
+		b, err := data.Asset(tr.asset)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	return tr.gridSum, nil
+}
+
+// sourceChecksum returns the checksum of the translation vector file the grid was
+// built from, which for a binary grid file is recorded in its header.
+func (tr *GridTransformer) sourceChecksum() (string, error) {
+	if tr.mapped != nil {
+		return tr.mapped.sourceSum, nil
+	}
+	return tr.checksum()
+}
+
+// checkExpectedChecksum verifies the grid if a checksum was given with WithChecksum.
+func (tr *GridTransformer) checkExpectedChecksum() error {
+	if tr.expectedChecksum == "" {
+		return nil
+	}
+	return tr.Verify()
+}
@@ -0,0 +1,152 @@
+package osgb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	tr := syntheticTransformer()
+	// A grid with no source file has no checksum
+	expected := GridMetadata{
+		Records:     nRecords,
+		MaxEasting:  700000,
+		MaxNorthing: 1250000,
+	}
+	if md := tr.Metadata(); md != expected {
+		t.Errorf("expected %+v, actual %+v", expected, md)
+	}
+	if err := tr.Verify(); err != ErrNoChecksum {
+		t.Errorf("expected %v for a custom grid, actual %v", ErrNoChecksum, err)
+	}
+
+	// The checksum of a grid loaded with WithGrid is the digest of the file read
+	grid := []byte(testGridHeader + strings.Join(testGridLines(nRecords), "\n"))
+	sum := sha256.Sum256(grid)
+	expected.Checksum = hex.EncodeToString(sum[:])
+	expected.SourceChecksum = expected.Checksum
+	tr, err := configureTransformer([]Option{WithGrid(bytes.NewReader(grid))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md := tr.Metadata(); md != expected {
+		t.Errorf("expected %+v, actual %+v", expected, md)
+	}
+
+	tr.model = ostn15Model
+	if md := tr.Metadata(); md.Model != "OSTN/OSGM" || md.Version != "15" || md.ReleaseDate != "2016-08" {
+		t.Errorf("expected OSTN/OSGM 15 released 2016-08, actual %+v", md)
+	}
+	tr.model = &gridModel{checksum: expected.Checksum}
+	if err := tr.Verify(); err != nil {
+		t.Errorf("expected recorded checksum to verify, actual %v", err)
+	}
+	tr.model.checksum = hex.EncodeToString(make([]byte, 32))
+	if err := tr.Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v, actual %v", ErrChecksumMismatch, err)
+	}
+}
+
+func TestMappedMetadata(t *testing.T) {
+	tr := syntheticTransformer()
+	var buf bytes.Buffer
+	if err := tr.WriteBinarySubGridNationalGrid(&buf, &OSGB36Coordinate{Easting: 250000, Northing: 650000}, &OSGB36Coordinate{Easting: 320000, Northing: 720000}); err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestFile(t, buf.Bytes())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	mapped, err := NewMappedTransformer(path, WithChecksum(checksum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	md := mapped.Metadata()
+	if md.Checksum != checksum {
+		t.Errorf("expected checksum of the file %s, actual %s", checksum, md.Checksum)
+	}
+	if md.MinEasting != 249000 || md.MinNorthing != 649000 || md.MaxEasting != 322000 || md.MaxNorthing != 722000 || md.Records != 74*74 {
+		t.Errorf("unexpected sub-grid extent %+v", md)
+	}
+	if err := mapped.Verify(); err != nil {
+		t.Errorf("expected checksum to verify, actual %v", err)
+	}
+	// The synthetic grid has no source file or model
+	if md.SourceChecksum != "" || md.Model != "" {
+		t.Errorf("expected no source, actual %+v", md)
+	}
+
+	if _, err := NewMappedTransformer(path, WithChecksum(hex.EncodeToString(make([]byte, 32)))); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v, actual %v", ErrChecksumMismatch, err)
+	}
+	if _, err := NewMappedTransformer(path, WithChecksum("not a checksum")); err == nil {
+		t.Error("expected invalid checksum to be rejected")
+	}
+
+	if err := mapped.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mapped.Verify(); err != ErrGridClosed {
+		t.Errorf("expected %v, actual %v", ErrGridClosed, err)
+	}
+}
+
+func TestMappedSourceMetadata(t *testing.T) {
+	grid := []byte(testGridHeader + strings.Join(testGridLines(nRecords), "\n"))
+	tr, err := configureTransformer([]Option{WithGrid(bytes.NewReader(grid))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.model = ostn15Model
+	source := tr.Metadata()
+
+	mapped, err := NewMappedTransformer(writeTestGridFile(t, tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	md := mapped.Metadata()
+	if md.Model != source.Model || md.Version != source.Version || md.ReleaseDate != source.ReleaseDate {
+		t.Errorf("expected model %s %s released %s, actual %+v", source.Model, source.Version, source.ReleaseDate, md)
+	}
+	if md.SourceChecksum != source.Checksum || md.Checksum == source.Checksum {
+		t.Errorf("expected source checksum %s, actual %+v", source.Checksum, md)
+	}
+
+	// Verify checks the recorded source against the release
+	if err := mapped.Verify(); err != ErrNoChecksum {
+		t.Errorf("expected %v without a recorded release checksum, actual %v", ErrNoChecksum, err)
+	}
+	mapped.model = &gridModel{checksum: source.Checksum}
+	if err := mapped.Verify(); err != nil {
+		t.Errorf("expected recorded source checksum to verify, actual %v", err)
+	}
+	mapped.model.checksum = hex.EncodeToString(make([]byte, 32))
+	if err := mapped.Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v, actual %v", ErrChecksumMismatch, err)
+	}
+
+	// A custom model is reported, but has no release checksum
+	tr.model = &gridModel{name: "custom", version: "1", releaseDate: "2020"}
+	custom, err := NewMappedTransformer(writeTestGridFile(t, tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer custom.Close()
+	if md := custom.Metadata(); md.Model != "custom" || md.Version != "1" || md.ReleaseDate != "2020" {
+		t.Errorf("expected custom model, actual %+v", md)
+	}
+	if err := custom.Verify(); err != ErrNoChecksum {
+		t.Errorf("expected %v, actual %v", ErrNoChecksum, err)
+	}
+}
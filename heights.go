@@ -0,0 +1,132 @@
+package osgb
+
+// VerticalDatum is the datum an orthometric height is given in. Each geoid
+// region of the OSGM models realises one vertical datum.
+type VerticalDatum struct {
+	// Name of the datum
+	Name string
+	// EPSG code of the datum's vertical coordinate reference system
+	EPSG int
+}
+
+// Vertical datums of the OSGM02 and OSGM15 geoid regions.
+var (
+	VerticalDatumNewlyn         = VerticalDatum{Name: "Ordnance Datum Newlyn", EPSG: 5701}
+	VerticalDatumStMarys        = VerticalDatum{Name: "St Marys", EPSG: 5749}
+	VerticalDatumDouglas02      = VerticalDatum{Name: "Douglas02", EPSG: 5750}
+	VerticalDatumStornoway      = VerticalDatum{Name: "Stornoway", EPSG: 5746}
+	VerticalDatumStKilda        = VerticalDatum{Name: "St Kilda", EPSG: 5747}
+	VerticalDatumLerwick        = VerticalDatum{Name: "Lerwick", EPSG: 5742}
+	VerticalDatumKirkwall       = VerticalDatum{Name: "Kirkwall", EPSG: 5740}
+	VerticalDatumFairIsle       = VerticalDatum{Name: "Fair Isle", EPSG: 5741}
+	VerticalDatumFlannanIsles   = VerticalDatum{Name: "Flannan Isles", EPSG: 5748}
+	VerticalDatumNorthRona      = VerticalDatum{Name: "North Rona", EPSG: 5745}
+	VerticalDatumSuleSkerry     = VerticalDatum{Name: "Sule Skerry", EPSG: 5744}
+	VerticalDatumFoula          = VerticalDatum{Name: "Foula", EPSG: 5743}
+	VerticalDatumMalinHead      = VerticalDatum{Name: "Malin Head", EPSG: 5731}
+	VerticalDatumBelfast        = VerticalDatum{Name: "Belfast", EPSG: 5732}
+	VerticalDatumNewlynOffshore = VerticalDatum{Name: "Ordnance Datum Newlyn (Offshore)", EPSG: 7707}
+	regionVerticalDatums        = map[GeoidRegion]VerticalDatum{
+		Region_UK_MAINLAND:         VerticalDatumNewlyn,
+		Region_SCILLY_ISLES:        VerticalDatumStMarys,
+		Region_ISLE_OF_MAN:         VerticalDatumDouglas02,
+		Region_OUTER_HEBRIDES:      VerticalDatumStornoway,
+		Region_ST_KILDA:            VerticalDatumStKilda,
+		Region_SHETLAND_ISLES:      VerticalDatumLerwick,
+		Region_ORKNEY_ISLES:        VerticalDatumKirkwall,
+		Region_FAIR_ISLE:           VerticalDatumFairIsle,
+		Region_FLANNAN_ISLES:       VerticalDatumFlannanIsles,
+		Region_NORTH_RONA:          VerticalDatumNorthRona,
+		Region_SULE_SKERRY:         VerticalDatumSuleSkerry,
+		Region_FOULA:               VerticalDatumFoula,
+		Region_REPUBLIC_OF_IRELAND: VerticalDatumMalinHead,
+		Region_NORTHERN_IRELAND:    VerticalDatumBelfast,
+		Region_OFFSHORE:            VerticalDatumNewlynOffshore,
+	}
+)
+
+// VerticalDatum returns the vertical datum of the geoid region,
+// or false if the region has no vertical datum.
+func (r GeoidRegion) VerticalDatum() (VerticalDatum, bool) {
+	datum, ok := regionVerticalDatums[r]
+	return datum, ok
+}
+
+func (d VerticalDatum) String() string {
+	return d.Name
+}
+
+// HeightTransformation is the result of transforming a height at a known ETRS89 position.
+type HeightTransformation struct {
+	// Height in metres
+	Height float64
+	// GeoidSeparation is the interpolated OSGM geoid-ellipsoid separation in metres,
+	// the ETRS89 ellipsoidal height minus the orthometric height.
+	GeoidSeparation float64
+	// Region is the geoid region nearest the position.
+	Region GeoidRegion
+	// Datum is the vertical datum of the orthometric height.
+	Datum VerticalDatum
+}
+
+// ToOrthometricHeight converts an ETRS89 ellipsoidal height at an ETRS89 position
+// to an orthometric height, without transforming the position.
+func (tr *GridTransformer) ToOrthometricHeight(lon, lat, ellipsoidalHeight float64) (*HeightTransformation, error) {
+	separation, region, err := tr.geoidSeparation(lon, lat)
+	if err != nil {
+		return nil, err
+	}
+	return &HeightTransformation{
+		Height:          ellipsoidalHeight - separation,
+		GeoidSeparation: separation,
+		Region:          region,
+		Datum:           regionVerticalDatums[region],
+	}, nil
+}
+
+// ToEllipsoidalHeight converts an orthometric height at an ETRS89 position
+// to an ETRS89 ellipsoidal height, without transforming the position.
+func (tr *GridTransformer) ToEllipsoidalHeight(lon, lat, orthometricHeight float64) (*HeightTransformation, error) {
+	separation, region, err := tr.geoidSeparation(lon, lat)
+	if err != nil {
+		return nil, err
+	}
+	return &HeightTransformation{
+		Height:          orthometricHeight + separation,
+		GeoidSeparation: separation,
+		Region:          region,
+		Datum:           regionVerticalDatums[region],
+	}, nil
+}
+
+// ToNationalGridInDatum coverts a coordinate position from ETRS89 to OSGB36, with its
+// height in the given vertical datum. ErrPointOutsideDatum is returned if the position
+// is not in the datum's geoid region.
+func (tr *GridTransformer) ToNationalGridInDatum(c *ETRS89Coordinate, datum VerticalDatum) (*OSGB36Coordinate, error) {
+	osgb36Coord, err := tr.ToNationalGrid(c)
+	if err != nil {
+		return nil, err
+	}
+	if osgb36Coord.VerticalDatum != datum {
+		return nil, ErrPointOutsideDatum
+	}
+	return osgb36Coord, nil
+}
+
+// geoidSeparation interpolates the geoid-ellipsoid separation at an ETRS89 position.
+func (tr *GridTransformer) geoidSeparation(lon, lat float64) (float64, GeoidRegion, error) {
+	etrs89Coord := nationalGridGRS80.toPlaneCoord(degreesToRadians(lat), degreesToRadians(lon))
+	if err := tr.acquireGrid(); err != nil {
+		return 0, 0, err
+	}
+	defer tr.releaseGrid()
+	rs, err := tr.getShiftRecords(&etrs89Coord)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, _, geoidHeight, err := tr.interpolate(&etrs89Coord, &rs)
+	if err != nil {
+		return 0, 0, err
+	}
+	return geoidHeight, nearestGeoidRegion(&etrs89Coord, &rs), nil
+}
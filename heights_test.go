@@ -0,0 +1,152 @@
+package osgb
+
+import (
+	"testing"
+)
+
+func Test15HeightTransformationData(t *testing.T) {
+	inputs, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pointID, input := range inputs {
+		output, ok := outputs[pointID]
+		if !ok {
+			t.Fatal("missing point ID in output ", pointID)
+		}
+
+		orthometric, err := trans.ToOrthometricHeight(input.etrs89Lon, input.etrs89Lat, input.etrs89Height)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+		checkDistance(t, pointID+" orthometric height", output.odnHeight, orthometric.Height)
+		checkDistance(t, pointID+" geoid separation", input.etrs89Height-output.odnHeight, orthometric.GeoidSeparation)
+		checkRegion(t, pointID+" region", output.geoidModelID, orthometric.Region)
+		if datum, _ := output.geoidModelID.VerticalDatum(); orthometric.Datum != datum {
+			t.Errorf("point ID %s: expected vertical datum %s, actual %s", pointID, datum, orthometric.Datum)
+		}
+
+		ellipsoidal, err := trans.ToEllipsoidalHeight(input.etrs89Lon, input.etrs89Lat, output.odnHeight)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+		checkDistance(t, pointID+" ellipsoidal height", input.etrs89Height, ellipsoidal.Height)
+	}
+}
+
+func Test15HeightTransformation(t *testing.T) {
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	etrs89Lat, err := dmsToDecimal(52, 39, 28.8282, north)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etrs89Lon, err := dmsToDecimal(1, 42, 57.8663, east)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	height, err := trans.ToOrthometricHeight(etrs89Lon, etrs89Lat, 108.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "orthometric height", 63.822, height.Height)
+	checkRegion(t, "region", Region_UK_MAINLAND, height.Region)
+	if height.Datum != VerticalDatumNewlyn {
+		t.Errorf("expected %s vertical datum, actual %s", VerticalDatumNewlyn, height.Datum)
+	}
+}
+
+func Test15VerticalDatum(t *testing.T) {
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TP01, Scilly Isles
+	tp01 := &ETRS89Coordinate{
+		Lat:    49.92226393730,
+		Lon:    -6.29977752014,
+		Height: 100.0,
+	}
+
+	osgb36Coord, err := trans.ToNationalGrid(tp01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if osgb36Coord.VerticalDatum != VerticalDatumStMarys {
+		t.Errorf("expected %s vertical datum, actual %s", VerticalDatumStMarys, osgb36Coord.VerticalDatum)
+	}
+
+	if _, err := trans.ToNationalGridInDatum(tp01, VerticalDatumStMarys); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := trans.ToNationalGridInDatum(tp01, VerticalDatumNewlyn); err != ErrPointOutsideDatum {
+		t.Errorf("expected ErrPointOutsideDatum, actual %v", err)
+	}
+
+	if _, err := trans.FromNationalGrid(osgb36Coord); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	osgb36Coord.VerticalDatum = VerticalDatumNewlyn
+	if _, err := trans.FromNationalGrid(osgb36Coord); err != ErrPointOutsideDatum {
+		t.Errorf("expected ErrPointOutsideDatum, actual %v", err)
+	}
+	// Heights with no datum are accepted anywhere.
+	osgb36Coord.VerticalDatum = VerticalDatum{}
+	if _, err := trans.FromNationalGrid(osgb36Coord); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestVerticalDatumEPSG(t *testing.T) {
+	tests := []struct {
+		region GeoidRegion
+		datum  VerticalDatum
+		epsg   int
+	}{
+		{Region_UK_MAINLAND, VerticalDatumNewlyn, 5701},
+		{Region_SCILLY_ISLES, VerticalDatumStMarys, 5749},
+		{Region_ISLE_OF_MAN, VerticalDatumDouglas02, 5750},
+		{Region_OUTER_HEBRIDES, VerticalDatumStornoway, 5746},
+		{Region_ST_KILDA, VerticalDatumStKilda, 5747},
+		{Region_SHETLAND_ISLES, VerticalDatumLerwick, 5742},
+		{Region_ORKNEY_ISLES, VerticalDatumKirkwall, 5740},
+		{Region_FAIR_ISLE, VerticalDatumFairIsle, 5741},
+		{Region_FLANNAN_ISLES, VerticalDatumFlannanIsles, 5748},
+		{Region_NORTH_RONA, VerticalDatumNorthRona, 5745},
+		{Region_SULE_SKERRY, VerticalDatumSuleSkerry, 5744},
+		{Region_FOULA, VerticalDatumFoula, 5743},
+		{Region_REPUBLIC_OF_IRELAND, VerticalDatumMalinHead, 5731},
+		{Region_NORTHERN_IRELAND, VerticalDatumBelfast, 5732},
+		{Region_OFFSHORE, VerticalDatumNewlynOffshore, 7707},
+	}
+	if len(tests) != len(regionVerticalDatums) {
+		t.Errorf("expected %d vertical datums, actual %d", len(tests), len(regionVerticalDatums))
+	}
+	for _, test := range tests {
+		if test.datum.EPSG != test.epsg {
+			t.Errorf("%s: expected EPSG %d, actual %d", test.datum, test.epsg, test.datum.EPSG)
+		}
+		datum, ok := test.region.VerticalDatum()
+		if !ok || datum != test.datum {
+			t.Errorf("region %d: expected %s vertical datum, actual %s", test.region, test.datum, datum)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package osgb
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	milliArcSecondInRadians = math.Pi / (180 * 3600 * 1000)
+)
+
+// helmert is a 7 parameter similarity transformation between Cartesian
+// coordinates, in the position vector convention:
+//
+//	X' = T + (1 + D)X + R × X
+//
+// Time-dependent transformations also give the rate of change of each
+// parameter per year from a reference epoch.
+type helmert struct {
+	// Translations in metres
+	tx, ty, tz float64
+	// Scale difference, unitless
+	d float64
+	// Rotations in radians
+	rx, ry, rz float64
+
+	// Rates of change per year, and the epoch they apply from as a decimal year
+	rates *helmert
+	epoch float64
+}
+
+// at returns the parameters of a time-dependent transformation at an epoch.
+func (h *helmert) at(epoch float64) *helmert {
+	if h.rates == nil {
+		return h
+	}
+	dt := epoch - h.epoch
+	return &helmert{
+		tx: h.tx + h.rates.tx*dt,
+		ty: h.ty + h.rates.ty*dt,
+		tz: h.tz + h.rates.tz*dt,
+		d:  h.d + h.rates.d*dt,
+		rx: h.rx + h.rates.rx*dt,
+		ry: h.ry + h.rates.ry*dt,
+		rz: h.rz + h.rates.rz*dt,
+	}
+}
+
+func (h *helmert) apply(c *cartesianCoord) *cartesianCoord {
+	s := 1 + h.d
+	return &cartesianCoord{
+		x: h.tx + s*c.x - h.rz*c.y + h.ry*c.z,
+		y: h.ty + h.rz*c.x + s*c.y - h.rx*c.z,
+		z: h.tz - h.ry*c.x + h.rx*c.y + s*c.z,
+	}
+}
+
+// applyInverse reverses the transformation exactly, by solving the linear system
+// rather than negating the parameters.
+func (h *helmert) applyInverse(c *cartesianCoord) *cartesianCoord {
+	s := 1 + h.d
+	m := [3][3]float64{
+		{s, -h.rz, h.ry},
+		{h.rz, s, -h.rx},
+		{-h.ry, h.rx, s},
+	}
+	v := [3]float64{c.x - h.tx, c.y - h.ty, c.z - h.tz}
+	det := func(m [3][3]float64) float64 {
+		return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	}
+	// Cramer's rule: replace each column of m with v in turn.
+	d := det(m)
+	var res [3]float64
+	for col := range res {
+		mc := m
+		for row := range v {
+			mc[row][col] = v[row]
+		}
+		res[col] = det(mc) / d
+	}
+	return &cartesianCoord{x: res[0], y: res[1], z: res[2]}
+}
+
+// ReferenceFrame is a realisation of the International Terrestrial Reference System,
+// in which GNSS positions are given at their observation epoch.
+type ReferenceFrame int
+
+const (
+	ITRF2014 ReferenceFrame = iota
+	ITRF2008
+)
+
+const (
+	// WGS84G2139 is aligned with ITRF2014 at the centimetre level.
+	WGS84G2139 = ITRF2014
+	// WGS84G1762 is aligned with ITRF2008 at the centimetre level.
+	WGS84G1762 = ITRF2008
+)
+
+var referenceFrameNames = map[ReferenceFrame]string{
+	ITRF2014: "ITRF2014",
+	ITRF2008: "ITRF2008",
+}
+
+func (f ReferenceFrame) String() string {
+	if name, ok := referenceFrameNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("ReferenceFrame(%d)", int(f))
+}
+
+// itrfToETRF2000 are the transformations from each ITRF realisation to ETRF2000,
+// from Altamimi, "EUREF Technical Note 1: Relationship and Transformation
+// between the International and the European Terrestrial Reference Systems".
+var itrfToETRF2000 = map[ReferenceFrame]*helmert{
+	ITRF2014: {
+		tx: 0.0547, ty: 0.0522, tz: -0.0741,
+		d:  2.12e-9,
+		rx: 1.701 * milliArcSecondInRadians, ry: 10.290 * milliArcSecondInRadians, rz: -16.632 * milliArcSecondInRadians,
+		rates: &helmert{
+			tx: 0.0001, ty: 0.0001, tz: -0.0019,
+			d:  0.11e-9,
+			rx: 0.081 * milliArcSecondInRadians, ry: 0.490 * milliArcSecondInRadians, rz: -0.792 * milliArcSecondInRadians,
+		},
+		epoch: 2010.0,
+	},
+	ITRF2008: {
+		tx: 0.0521, ty: 0.0493, tz: -0.0585,
+		d:  1.34e-9,
+		rx: 0.891 * milliArcSecondInRadians, ry: 5.390 * milliArcSecondInRadians, rz: -8.712 * milliArcSecondInRadians,
+		rates: &helmert{
+			tx: 0.0001, ty: 0.0001, tz: -0.0018,
+			d:  0.08e-9,
+			rx: 0.081 * milliArcSecondInRadians, ry: 0.490 * milliArcSecondInRadians, rz: -0.792 * milliArcSecondInRadians,
+		},
+		epoch: 2000.0,
+	},
+}
+
+// ITRFCoordinate represents a coordinate position in an ITRF realisation, or the WGS84
+// realisation aligned with it, at the epoch it was observed.
+type ITRFCoordinate struct {
+	// Longitude in decimal degrees
+	Lon float64
+	// Latitude in decimal degrees
+	Lat float64
+	// Height above the GRS80 ellipsoid in metres
+	Height float64
+	// Frame the position is given in
+	Frame ReferenceFrame
+	// Epoch of the observation as a decimal year, see Epoch
+	Epoch float64
+}
+
+// NewITRFCoord creates a new coordinate position in an ITRF realisation at an observation epoch.
+func NewITRFCoord(frame ReferenceFrame, lon, lat, height, epoch float64) *ITRFCoordinate {
+	return &ITRFCoordinate{
+		Lon:    lon,
+		Lat:    lat,
+		Height: height,
+		Frame:  frame,
+		Epoch:  epoch,
+	}
+}
+
+// Epoch returns a time as a decimal year, as used for ITRF observation epochs.
+func Epoch(t time.Time) float64 {
+	t = t.UTC()
+	start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	return float64(t.Year()) + float64(t.Sub(start))/float64(end.Sub(start))
+}
+
+// ToETRS89 transforms the position to ETRS89, as realised by ETRF2000, using the
+// 14 parameter transformation recommended by EUREF at the observation epoch.
+// The result is at the observation epoch: in Great Britain ETRS89 positions drift
+// by no more than a few millimetres a year, so no further propagation is applied.
+// ETRF2000 and the ETRS89 realisation of OS Net agree to a few centimetres, which
+// is within the accuracy of OSTN15. ErrNonFinite is returned for NaN or infinite
+// coordinates or epochs.
+func (c *ITRFCoordinate) ToETRS89() (*ETRS89Coordinate, error) {
+	h, ok := itrfToETRF2000[c.Frame]
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference frame %s", c.Frame)
+	}
+	if !finite(c.Lon) || !finite(c.Lat) || !finite(c.Height) || !finite(c.Epoch) {
+		return nil, ErrNonFinite
+	}
+	itrfCoord := grs80Ellipsoid.geographicToCartesian(&geographicCoord{
+		lat:    degreesToRadians(c.Lat),
+		lon:    degreesToRadians(c.Lon),
+		height: c.Height,
+	})
+	etrs89Coord, err := grs80Ellipsoid.cartesianToGeographic(h.at(c.Epoch).apply(itrfCoord))
+	if err != nil {
+		return nil, err
+	}
+	return &ETRS89Coordinate{
+		Lat:    radiansToDegrees(etrs89Coord.lat),
+		Lon:    radiansToDegrees(etrs89Coord.lon),
+		Height: etrs89Coord.height,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package osgb
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHelmertAt(t *testing.T) {
+	h := itrfToETRF2000[ITRF2014].at(2020.0)
+	checkDistance(t, "tz", -0.0931, h.tz)
+	checkAngle(t, "rz", -24.552*milliArcSecondInRadians, h.rz)
+	if h.rates != nil {
+		t.Error("expected parameters at an epoch to have no rates")
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	testData := []struct {
+		time     time.Time
+		expected float64
+	}{
+		{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 2020.0},
+		{time.Date(2020, 7, 2, 0, 0, 0, 0, time.UTC), 2020.5},
+		{time.Date(2021, 7, 2, 12, 0, 0, 0, time.UTC), 2021.5},
+	}
+	for _, d := range testData {
+		if actual := Epoch(d.time); math.Abs(actual-d.expected) > 1e-9 {
+			t.Errorf("%s: expected %f, actual %f", d.time, d.expected, actual)
+		}
+	}
+}
+
+func TestITRFToETRS89(t *testing.T) {
+	const lon, lat, height = -0.1262, 51.5080, 50.0
+	// The expected ETRF2000 positions were computed separately from the
+	// transformation parameters and rates of EUREF Technical Note 1, in their
+	// published units of millimetres, parts per billion and milliarcseconds.
+	testData := []struct {
+		frame   ReferenceFrame
+		x, y, z float64
+	}{
+		{ITRF2014, 3977973.8254, -8762.4011, 4968955.2506},
+		{WGS84G1762, 3977973.8227, -8762.4030, 4968955.2478},
+	}
+	for _, d := range testData {
+		etrs89Coord, err := NewITRFCoord(d.frame, lon, lat, height, 2020.0).ToETRS89()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cartCoord := grs80Ellipsoid.geographicToCartesian(&geographicCoord{
+			lat:    degreesToRadians(etrs89Coord.Lat),
+			lon:    degreesToRadians(etrs89Coord.Lon),
+			height: etrs89Coord.Height,
+		})
+		checkDistance(t, d.frame.String()+" x", d.x, cartCoord.x)
+		checkDistance(t, d.frame.String()+" y", d.y, cartCoord.y)
+		checkDistance(t, d.frame.String()+" z", d.z, cartCoord.z)
+	}
+
+	if _, err := NewITRFCoord(ReferenceFrame(99), lon, lat, height, 2020.0).ToETRS89(); err == nil {
+		t.Error("expected error for unsupported reference frame")
+	}
+	for _, c := range []*ITRFCoordinate{
+		NewITRFCoord(ITRF2014, math.NaN(), lat, height, 2020.0),
+		NewITRFCoord(ITRF2014, lon, lat, math.Inf(1), 2020.0),
+		NewITRFCoord(ITRF2014, lon, lat, height, math.NaN()),
+	} {
+		if _, err := c.ToETRS89(); err != ErrNonFinite {
+			t.Errorf("%+v: expected %v, actual %v", *c, ErrNonFinite, err)
+		}
+	}
+}
@@ -17,9 +17,9 @@ func checkAngle(t *testing.T, name string, expected, actual float64) {
 	}
 }
 
-func checkRegion(t *testing.T, name string, expected, actual geoidRegion) {
+func checkRegion(t *testing.T, name string, expected, actual GeoidRegion) {
 	if expected != actual {
-		t.Errorf("%s: expected %d, actual %d", name, expected, actual)
+		t.Errorf("%s: expected %d (%s), actual %d (%s)", name, expected, expected, actual, actual)
 	}
 }
 
@@ -33,3 +33,37 @@ func equalDistance(a, b float64) bool {
 	const epsilon = 0.001
 	return math.Abs(a-b) < epsilon
 }
+
+// testTransformer returns a transformer on a synthetic grid with zero shifts
+// and the geoid region of each record given by its east and north indices.
+func testTransformer(region func(e, n int) GeoidRegion) *GridTransformer {
+	records := make([]record, nRecords)
+	for i := range records {
+		easting, northing := recordPosition(uint32(i + 1))
+		records[i] = record{
+			recordNo:       uint32(i + 1),
+			etrs89Easting:  easting,
+			etrs89Northing: northing,
+			geoidRegion:    region(i%nEastIndices, i/nEastIndices),
+		}
+	}
+	return &GridTransformer{
+		records:       records,
+		tolerance:     DefaultTolerance,
+		maxIterations: DefaultMaxIterations,
+	}
+}
+
+// syntheticTransformer returns a transformer on a synthetic grid with smoothly
+// varying shifts and geoid heights, all in the UK mainland geoid region.
+func syntheticTransformer() *GridTransformer {
+	tr := testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND })
+	for i := range tr.records {
+		rec := &tr.records[i]
+		e, n := float64(rec.etrs89Easting)/1000, float64(rec.etrs89Northing)/1000
+		rec.ostnEastShift = -100 + 0.5*math.Sin(e/50) + 0.001*n
+		rec.ostnNorthShift = 80 + 0.5*math.Cos(n/70) - 0.002*e
+		rec.ostnGeoidHeight = 50 + math.Sin(e/30)*math.Cos(n/40)
+	}
+	return tr
+}
@@ -0,0 +1,175 @@
+package osgb
+
+import (
+	"math"
+)
+
+// Interpolation selects how shifts are interpolated between grid records.
+//
+// OSTN and OSGM are defined using bilinear interpolation, and only
+// InterpolationBilinear reproduces the OS reference software. Bicubic and
+// biquadratic interpolation follow the curvature of the shift surfaces for
+// research comparisons, and differ from the OS definition by up to a few
+// millimetres where the surfaces are curved.
+type Interpolation int
+
+const (
+	// InterpolationBilinear interpolates the four records surrounding a position.
+	// This is the OS defined method and the default.
+	InterpolationBilinear Interpolation = iota
+	// InterpolationBicubic fits Catmull-Rom cubic convolution splines through the
+	// 4x4 records surrounding a position. The interpolated surface passes through
+	// the records and has continuous gradients.
+	//
+	// The 4x4 records are unavailable in the outer row of cells of the grid and
+	// in cells next to records outside the transformation. Positions there
+	// silently fall back to bilinear interpolation, so the interpolated surface
+	// is not continuous where the two methods meet.
+	InterpolationBicubic
+	// InterpolationBiquadratic fits quadratic polynomials through the 3x3 records
+	// around the grid node nearest a position, as PROJ's biquadratic grid
+	// interpolation does, following NOAA Technical Memorandum NOS NGS 84. The
+	// interpolated surface passes through the records, but steps where the
+	// nearest node changes, at the half-way lines between records.
+	//
+	// In the outer cells of the grid the 3x3 records are taken from inside the
+	// grid instead. Positions next to records outside the transformation
+	// silently fall back to bilinear interpolation, as for InterpolationBicubic.
+	InterpolationBiquadratic
+)
+
+func (i Interpolation) String() string {
+	switch i {
+	case InterpolationBilinear:
+		return "bilinear"
+	case InterpolationBicubic:
+		return "bicubic"
+	case InterpolationBiquadratic:
+		return "biquadratic"
+	}
+	return "unknown"
+}
+
+// interpolate returns the east, north and geoid height shifts at an ETRS89
+// grid position, surrounded by the records rs, using the transformer's interpolation.
+func (tr *GridTransformer) interpolate(etrs89Coord *planeCoord, rs *shiftRecords) (float64, float64, float64, error) {
+	var shiftEast, shiftNorth, geoidHeight float64
+	var ok bool
+	var err error
+	switch tr.interpolation {
+	case InterpolationBicubic:
+		shiftEast, shiftNorth, geoidHeight, ok, err = tr.bicubic(etrs89Coord)
+	case InterpolationBiquadratic:
+		shiftEast, shiftNorth, geoidHeight, ok, err = tr.biquadratic(etrs89Coord)
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if !ok {
+		shiftEast, shiftNorth, geoidHeight = rs.shifts(etrs89Coord)
+	}
+	return shiftEast, shiftNorth, geoidHeight, nil
+}
+
+// neighbourhood fills recs with the records of a size x size block of grid nodes
+// with its bottom left node at the given indices, or returns false if any is
+// outside the grid or the transformation.
+func (tr *GridTransformer) neighbourhood(recs []record, eastIndex, northIndex, size int) (bool, error) {
+	for j := 0; j < size; j++ {
+		for i := 0; i < size; i++ {
+			e, n := eastIndex+i, northIndex+j
+			if e < 0 || n < 0 || e >= nEastIndices || n >= nNorthIndices {
+				return false, nil
+			}
+			rec, err := tr.lookupShiftRecord(uint32(e), uint32(n))
+			if err == ErrPointOutsidePolygon || err == ErrPointOutsideTransformation {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			recs[i+j*size] = rec
+		}
+	}
+	return true, nil
+}
+
+func (tr *GridTransformer) bicubic(etrs89Coord *planeCoord) (float64, float64, float64, bool, error) {
+	eastIndex := math.Floor(etrs89Coord.easting / 1000.0)
+	northIndex := math.Floor(etrs89Coord.northing / 1000.0)
+	var recs [16]record
+	if ok, err := tr.neighbourhood(recs[:], int(eastIndex)-1, int(northIndex)-1, 4); !ok {
+		return 0, 0, 0, false, err
+	}
+	wx := catmullRomWeights(etrs89Coord.easting/1000.0 - eastIndex)
+	wy := catmullRomWeights(etrs89Coord.northing/1000.0 - northIndex)
+	shiftEast, shiftNorth, geoidHeight := weightedShifts(recs[:], wx[:], wy[:])
+	return shiftEast, shiftNorth, geoidHeight, true, nil
+}
+
+func (tr *GridTransformer) biquadratic(etrs89Coord *planeCoord) (float64, float64, float64, bool, error) {
+	eastIndex, s := quadraticWindow(etrs89Coord.easting/1000.0, nEastIndices)
+	northIndex, t := quadraticWindow(etrs89Coord.northing/1000.0, nNorthIndices)
+	var recs [9]record
+	if ok, err := tr.neighbourhood(recs[:], eastIndex, northIndex, 3); !ok {
+		return 0, 0, 0, false, err
+	}
+	wx := quadraticWeights(s)
+	wy := quadraticWeights(t)
+	shiftEast, shiftNorth, geoidHeight := weightedShifts(recs[:], wx[:], wy[:])
+	return shiftEast, shiftNorth, geoidHeight, true, nil
+}
+
+// quadraticWindow returns the index of the first of the three grid nodes used to
+// interpolate at a position x, in grid units, and the position relative to it.
+// The window is centred on the nearest node, as in PROJ, except at the edges of a
+// grid of n nodes where it is moved inside the grid.
+func quadraticWindow(x float64, n int) (int, float64) {
+	index := math.Floor(x)
+	s := x - index
+	i := int(index)
+	if (s <= 0.5 && i > 0) || i+2 == n {
+		i--
+		s++
+	}
+	return i, s
+}
+
+// quadraticWeights returns the Lagrange weights of the nodes at 0, 1 and 2
+// for a position s between them.
+func quadraticWeights(s float64) [3]float64 {
+	return [3]float64{
+		(s - 1) * (s - 2) / 2,
+		-s * (s - 2),
+		s * (s - 1) / 2,
+	}
+}
+
+// catmullRomWeights returns the weights of the nodes at -1, 0, 1 and 2
+// for a position t between nodes 0 and 1.
+func catmullRomWeights(t float64) [4]float64 {
+	t2 := t * t
+	t3 := t2 * t
+	return [4]float64{
+		(-t3 + 2*t2 - t) / 2,
+		(3*t3 - 5*t2 + 2) / 2,
+		(-3*t3 + 4*t2 + t) / 2,
+		(t3 - t2) / 2,
+	}
+}
+
+// weightedShifts sums the shifts of a block of records, stored row by row
+// from the south west, weighted by the product of their east and north weights.
+func weightedShifts(recs []record, wx, wy []float64) (float64, float64, float64) {
+	var shiftEast, shiftNorth, geoidHeight float64
+	for j, v := range wy {
+		for i, u := range wx {
+			rec := &recs[i+j*len(wx)]
+			w := u * v
+			shiftEast += w * rec.ostnEastShift
+			shiftNorth += w * rec.ostnNorthShift
+			geoidHeight += w * rec.ostnGeoidHeight
+		}
+	}
+	return shiftEast, shiftNorth, geoidHeight
+}
@@ -0,0 +1,246 @@
+package osgb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInterpolationLinearField(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		return Region_UK_MAINLAND
+	})
+	// Shifts varying linearly across the grid are reproduced exactly by every method.
+	for i := range tr.records {
+		rec := &tr.records[i]
+		rec.ostnEastShift = 90 + 0.001*float64(rec.etrs89Easting)/1000.0
+		rec.ostnNorthShift = -80 - 0.002*float64(rec.etrs89Northing)/1000.0
+		rec.ostnGeoidHeight = 50 + 0.003*float64(rec.etrs89Easting+rec.etrs89Northing)/1000.0
+	}
+
+	for _, interpolation := range []Interpolation{InterpolationBilinear, InterpolationBicubic, InterpolationBiquadratic} {
+		tr.interpolation = interpolation
+		for _, c := range []planeCoord{{351234.5, 456789.1}, {100000, 200000}, {123999.9, 987000.4}} {
+			rs, err := tr.getShiftRecords(&c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shiftEast, shiftNorth, geoidHeight, err := tr.interpolate(&c, &rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedEast := 90 + 0.001*c.easting/1000.0
+			expectedNorth := -80 - 0.002*c.northing/1000.0
+			expectedHeight := 50 + 0.003*(c.easting+c.northing)/1000.0
+			if math.Abs(shiftEast-expectedEast) > 1e-9 ||
+				math.Abs(shiftNorth-expectedNorth) > 1e-9 ||
+				math.Abs(geoidHeight-expectedHeight) > 1e-9 {
+				t.Errorf("%s: expected (%f, %f, %f), actual (%f, %f, %f)", interpolation,
+					expectedEast, expectedNorth, expectedHeight, shiftEast, shiftNorth, geoidHeight)
+			}
+		}
+	}
+}
+
+func TestInterpolationAtRecords(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		return Region_UK_MAINLAND
+	})
+	for i := range tr.records {
+		rec := &tr.records[i]
+		rec.ostnEastShift = math.Sin(float64(rec.etrs89Easting) / 7000.0)
+		rec.ostnNorthShift = math.Cos(float64(rec.etrs89Northing) / 5000.0)
+		rec.ostnGeoidHeight = math.Sin(float64(rec.etrs89Easting+rec.etrs89Northing) / 3000.0)
+	}
+
+	c := planeCoord{easting: 300000, northing: 400000}
+	rs, err := tr.getShiftRecords(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, interpolation := range []Interpolation{InterpolationBicubic, InterpolationBiquadratic} {
+		tr.interpolation = interpolation
+		shiftEast, shiftNorth, geoidHeight, err := tr.interpolate(&c, &rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shiftEast != rs.s0.ostnEastShift || shiftNorth != rs.s0.ostnNorthShift || geoidHeight != rs.s0.ostnGeoidHeight {
+			t.Errorf("%s: expected record shifts at grid node", tr.interpolation)
+		}
+	}
+}
+
+func TestInterpolationQuadraticField(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		// The outermost records are outside the transformation, so cells
+		// next to them need bilinear interpolation
+		if e == 0 || n == 0 {
+			return Region_OUTSIDE_TRANSFORMATION
+		}
+		return Region_UK_MAINLAND
+	})
+	// East shifts of x² and geoid heights of y², for x and y in kilometres.
+	for i := range tr.records {
+		rec := &tr.records[i]
+		x := float64(rec.etrs89Easting) / 1000.0
+		y := float64(rec.etrs89Northing) / 1000.0
+		rec.ostnEastShift = x * x
+		rec.ostnGeoidHeight = y * y
+	}
+
+	tests := []struct {
+		interpolation Interpolation
+		c             planeCoord
+		shiftEast     float64
+		geoidHeight   float64
+	}{
+		// Bilinear interpolation follows the chords between records.
+		{InterpolationBilinear, planeCoord{10500, 20250}, 110.5, 410.25},
+		// Catmull-Rom splines reproduce quadratics exactly.
+		{InterpolationBicubic, planeCoord{10500, 20250}, 110.25, 410.0625},
+		{InterpolationBicubic, planeCoord{2750, 3100}, 7.5625, 9.61},
+		// So do quadratics through the 3x3 records around the nearest record.
+		{InterpolationBiquadratic, planeCoord{10500, 20250}, 110.25, 410.0625},
+		{InterpolationBiquadratic, planeCoord{2750, 3100}, 7.5625, 9.61},
+		// Next to the records outside the transformation, both fall back to bilinear.
+		{InterpolationBicubic, planeCoord{1500, 20250}, 2.5, 410.25},
+		{InterpolationBicubic, planeCoord{10500, 1250}, 110.5, 1.75},
+		{InterpolationBiquadratic, planeCoord{1500, 20250}, 2.5, 410.25},
+		{InterpolationBiquadratic, planeCoord{10500, 1250}, 110.5, 1.75},
+	}
+	for _, test := range tests {
+		tr.interpolation = test.interpolation
+		rs, err := tr.getShiftRecords(&test.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shiftEast, _, geoidHeight, err := tr.interpolate(&test.c, &rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(shiftEast-test.shiftEast) > 1e-9 || math.Abs(geoidHeight-test.geoidHeight) > 1e-9 {
+			t.Errorf("%s at %+v: expected (%f, %f), actual (%f, %f)", test.interpolation, test.c,
+				test.shiftEast, test.geoidHeight, shiftEast, geoidHeight)
+		}
+	}
+}
+
+func Test15InterpolationDefault(t *testing.T) {
+	inputs, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaultTrans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bicubic, err := NewOSTN15Transformer(WithInterpolation(InterpolationBicubic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	biquadratic, err := NewOSTN15Transformer(WithInterpolation(InterpolationBiquadratic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pointID, input := range inputs {
+		output, ok := outputs[pointID]
+		if !ok {
+			t.Fatal("missing point ID in output ", pointID)
+		}
+		etrs89Coord := &ETRS89Coordinate{
+			Lat:    input.etrs89Lat,
+			Lon:    input.etrs89Lon,
+			Height: input.etrs89Height,
+		}
+
+		// The default is exactly the bilinear interpolation of the OS reference software.
+		etrs89Plane := etrs89ToPlaneCoord(etrs89Coord)
+		rs, err := defaultTrans.getShiftRecords(&etrs89Plane)
+		if err != nil {
+			if _, err := defaultTrans.ToNationalGrid(etrs89Coord); err == nil {
+				t.Errorf("point ID %s: expected an error outside the grid", pointID)
+			}
+			continue
+		}
+		shiftEast, shiftNorth, geoidHeight := rs.shifts(&etrs89Plane)
+		osgb36Coord, err := defaultTrans.ToNationalGrid(etrs89Coord)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+		if osgb36Coord.Easting != etrs89Plane.easting+shiftEast ||
+			osgb36Coord.Northing != etrs89Plane.northing+shiftNorth ||
+			osgb36Coord.Height != etrs89Coord.Height-geoidHeight {
+			t.Errorf("point ID %s: expected bilinear result (%f, %f, %f), actual %#v", pointID,
+				etrs89Plane.easting+shiftEast, etrs89Plane.northing+shiftNorth, etrs89Coord.Height-geoidHeight, osgb36Coord)
+		}
+		checkDistance(t, "osgb36 east", output.osgb36Easting, osgb36Coord.Easting)
+		checkDistance(t, "osgb36 north", output.osgb36Northing, osgb36Coord.Northing)
+		checkDistance(t, "orthometric height", output.odnHeight, osgb36Coord.Height)
+
+		// Bicubic and biquadratic interpolation stay within a few millimetres of the OS definition.
+		for _, other := range []*GridTransformer{bicubic, biquadratic} {
+			otherCoord, err := other.ToNationalGrid(etrs89Coord)
+			if err != nil {
+				t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+				continue
+			}
+			if math.Abs(otherCoord.Easting-osgb36Coord.Easting) > 0.005 ||
+				math.Abs(otherCoord.Northing-osgb36Coord.Northing) > 0.005 ||
+				math.Abs(otherCoord.Height-osgb36Coord.Height) > 0.005 {
+				t.Errorf("point ID %s: expected %s within 5mm of %#v, actual %#v", pointID, other.interpolation, osgb36Coord, otherCoord)
+			}
+		}
+	}
+}
+
+func TestInterpolationCubicField(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		return Region_UK_MAINLAND
+	})
+	// East shifts of x³, for x in kilometres, which neither method reproduces.
+	for i := range tr.records {
+		rec := &tr.records[i]
+		x := float64(rec.etrs89Easting) / 1000.0
+		rec.ostnEastShift = x * x * x
+	}
+
+	tests := []struct {
+		interpolation Interpolation
+		c             planeCoord
+		shiftEast     float64
+	}{
+		// Catmull-Rom splines through x = 9, 10, 11 and 12.
+		{InterpolationBicubic, planeCoord{10250, 20000}, 1076.984375},
+		{InterpolationBicubic, planeCoord{10750, 20000}, 1242.203125},
+		// Quadratics through the records nearest x = 10 and x = 11, which
+		// differ either side of the half-way line.
+		{InterpolationBiquadratic, planeCoord{10250, 20000}, 1077.125},
+		{InterpolationBiquadratic, planeCoord{10500, 20000}, 1158},
+		{InterpolationBiquadratic, planeCoord{10500.001, 20000}, 1157.250331},
+		{InterpolationBiquadratic, planeCoord{10750, 20000}, 1242.0625},
+		// At the edges of the grid, quadratics through the outermost three records.
+		{InterpolationBiquadratic, planeCoord{750, 20000}, 0.1875},
+		{InterpolationBiquadratic, planeCoord{699250, 20000}, 341898681.0625},
+	}
+	for _, test := range tests {
+		tr.interpolation = test.interpolation
+		rs, err := tr.getShiftRecords(&test.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shiftEast, _, _, err := tr.interpolate(&test.c, &rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(shiftEast-test.shiftEast) > 1e-6 {
+			t.Errorf("%s at %+v: expected %f, actual %f", test.interpolation, test.c, test.shiftEast, shiftEast)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package osgb
+
+import (
+	"math"
+	"sync"
+)
+
+// inverseGrid holds the ETRS89 minus OSGB36 easting and northing shifts and the
+// geoid height at each node of a 1km grid in OSGB36 coordinates, built from the
+// iterative transformation. Nodes the transformation fails at are NaN.
+type inverseGrid struct {
+	once   sync.Once
+	shifts [][3]float32
+}
+
+func (tr *GridTransformer) inverseShifts() [][3]float32 {
+	tr.inverse.once.Do(func() {
+		shifts := make([][3]float32, nRecords)
+		for i := range shifts {
+			easting, northing := recordPosition(uint32(i + 1))
+			osgb36Coord := planeCoord{
+				easting:  float64(easting),
+				northing: float64(northing),
+			}
+			etrs89Coord, geoidHeight, err := tr.iterateFromOSGB36(&osgb36Coord, 0, osgb36Coord, 0, nil)
+			if err != nil {
+				nan := float32(math.NaN())
+				shifts[i] = [3]float32{nan, nan, nan}
+				continue
+			}
+			shifts[i] = [3]float32{
+				float32(etrs89Coord.easting - osgb36Coord.easting),
+				float32(etrs89Coord.northing - osgb36Coord.northing),
+				float32(geoidHeight),
+			}
+		}
+		tr.inverse.shifts = shifts
+	})
+	return tr.inverse.shifts
+}
+
+// inverseEstimate bilinearly interpolates the inverse grid to estimate the ETRS89
+// position and height of an OSGB36 position, or returns false if the cell
+// surrounding the position is not fully transformable.
+func (tr *GridTransformer) inverseEstimate(osgb36Coord *planeCoord, odnHeight float64) (planeCoord, float64, bool) {
+	shifts := tr.inverseShifts()
+	eastIndex := eastingIndex(osgb36Coord.easting)
+	northIndex := northingIndex(osgb36Coord.northing)
+	if osgb36Coord.easting < 0 || osgb36Coord.northing < 0 ||
+		eastIndex+1 >= nEastIndices || northIndex+1 >= nNorthIndices {
+		return planeCoord{}, 0, false
+	}
+	s0 := shifts[eastIndex+northIndex*nEastIndices]
+	s1 := shifts[eastIndex+1+northIndex*nEastIndices]
+	s2 := shifts[eastIndex+1+(northIndex+1)*nEastIndices]
+	s3 := shifts[eastIndex+(northIndex+1)*nEastIndices]
+
+	t := osgb36Coord.easting/1000.0 - float64(eastIndex)
+	u := osgb36Coord.northing/1000.0 - float64(northIndex)
+	var res [3]float64
+	for i := range res {
+		res[i] = (1-t)*(1-u)*float64(s0[i]) +
+			t*(1-u)*float64(s1[i]) +
+			t*u*float64(s2[i]) +
+			(1-t)*u*float64(s3[i])
+		if math.IsNaN(res[i]) {
+			return planeCoord{}, 0, false
+		}
+	}
+	return planeCoord{
+		easting:  osgb36Coord.easting + res[0],
+		northing: osgb36Coord.northing + res[1],
+	}, odnHeight + res[2], true
+}
@@ -0,0 +1,75 @@
+package osgb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInverseGrid(t *testing.T) {
+	iterative, inverse := syntheticTransformer(), syntheticTransformer()
+	inverse.useInverseGrid = true
+	for _, c := range []OSGB36Coordinate{
+		{Easting: 91492.146, Northing: 11318.804, Height: 46.519},
+		{Easting: 331534.552, Northing: 431920.792, Height: 12.658},
+		{Easting: 651409.792, Northing: 313177.448, Height: 24.7},
+		{Easting: 400000, Northing: 1000000, Height: 100},
+	} {
+		osgb36Coord := &planeCoord{easting: c.Easting, northing: c.Northing}
+		expected, expectedHeight, err := iterative.fromOSGB36(osgb36Coord, c.Height, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diag := &Diagnostics{}
+		actual, actualHeight, err := inverse.fromOSGB36(osgb36Coord, c.Height, diag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(expected.easting-actual.easting) > 0.0001 ||
+			math.Abs(expected.northing-actual.northing) > 0.0001 ||
+			math.Abs(expectedHeight-actualHeight) > 0.0001 {
+			t.Errorf("(%f, %f): expected (%f, %f, %f), actual (%f, %f, %f)", c.Easting, c.Northing,
+				expected.easting, expected.northing, expectedHeight, actual.easting, actual.northing, actualHeight)
+		}
+		if diag.Iterations > 2 {
+			t.Errorf("(%f, %f): expected at most 2 iterations, actual %d", c.Easting, c.Northing, diag.Iterations)
+		}
+	}
+}
+
+func Test15InverseGridData(t *testing.T) {
+	inputs, err := read15OSGBToETRSInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterative, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inverse, err := NewOSTN15Transformer(WithInverseGrid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pointID, input := range inputs {
+		c := NewOSGB36Coord(input.osgbEasting, input.osgbNorthing, input.orthometricHeight)
+		expected, expectedErr := iterative.FromNationalGrid(c)
+		actual, diag, err := inverse.FromNationalGridWithDiagnostics(c)
+		if err != expectedErr {
+			t.Errorf("%s: expected error %v, actual %v", pointID, expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		const epsilon = 0.0001
+		etrs89Expected := nationalGridGRS80.toPlaneCoord(degreesToRadians(expected.Lat), degreesToRadians(expected.Lon))
+		etrs89Actual := nationalGridGRS80.toPlaneCoord(degreesToRadians(actual.Lat), degreesToRadians(actual.Lon))
+		if math.Abs(etrs89Expected.easting-etrs89Actual.easting) > epsilon ||
+			math.Abs(etrs89Expected.northing-etrs89Actual.northing) > epsilon ||
+			math.Abs(expected.Height-actual.Height) > epsilon {
+			t.Errorf("%s: expected %+v, actual %+v", pointID, expected, actual)
+		}
+		if diag.Iterations > 2 {
+			t.Errorf("%s: expected at most 2 iterations, actual %d", pointID, diag.Iterations)
+		}
+	}
+}
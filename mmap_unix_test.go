@@ -0,0 +1,47 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package osgb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// A shared mapping sees changes made to the file after it is opened, so Verify
+// must check the file as it is now.
+func TestMappedVerifyChangedFile(t *testing.T) {
+	tr := syntheticTransformer()
+	path := writeTestGridFile(t, tr)
+	unpinned, err := NewMappedTransformer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unpinned.Close()
+	if err := unpinned.Verify(); err != ErrNoChecksum {
+		t.Errorf("expected %v, actual %v", ErrNoChecksum, err)
+	}
+	checksum := unpinned.Metadata().Checksum
+	mapped, err := NewMappedTransformer(path, WithChecksum(checksum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// Change the east shift of the first record
+	if _, err := f.WriteAt([]byte{1}, gridFileHeaderSize); err != nil {
+		t.Fatal(err)
+	}
+	if err := mapped.Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v, actual %v", ErrChecksumMismatch, err)
+	}
+	// Without an expected checksum the records are checked against the header
+	if err := unpinned.Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v, actual %v", ErrChecksumMismatch, err)
+	}
+}
@@ -0,0 +1,510 @@
+package osgb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	ntv2RecordSize       = 16
+	ntv2HeaderRecords    = 11
+	ntv2NodeSize         = 16
+	ntv2SecondsPerDegree = 3600.0
+	// Iterating the inverse shift stops when successive positions are
+	// within this many arc seconds, around 0.03mm.
+	ntv2Tolerance = 1e-6
+)
+
+var errNTv2Format = errors.New("invalid NTv2 grid")
+
+// GeographicExtent describes the extent and node spacing of a latitude and longitude grid, in decimal degrees.
+type GeographicExtent struct {
+	South, North float64
+	West, East   float64
+	// LatIncrement is the spacing between grid rows
+	LatIncrement float64
+	// LonIncrement is the spacing between grid columns
+	LonIncrement float64
+}
+
+// DefaultGeographicExtent covers the OSTN15 transformation grid at 30 arc second spacing.
+var DefaultGeographicExtent = GeographicExtent{
+	South:        49.75,
+	North:        61.0,
+	West:         -10.5,
+	East:         2.0,
+	LatIncrement: 30 / ntv2SecondsPerDegree,
+	LonIncrement: 30 / ntv2SecondsPerDegree,
+}
+
+// dims returns the number of rows and columns of grid nodes in the extent.
+func (extent GeographicExtent) dims() (int, int, error) {
+	if !(extent.LatIncrement > 0) || !(extent.LonIncrement > 0) || extent.North <= extent.South || extent.East <= extent.West {
+		return 0, 0, fmt.Errorf("invalid grid extent %+v", extent)
+	}
+	nRows := int(math.Floor((extent.North-extent.South)/extent.LatIncrement+0.5)) + 1
+	nCols := int(math.Floor((extent.East-extent.West)/extent.LonIncrement+0.5)) + 1
+	if nRows < 2 || nCols < 2 {
+		return 0, 0, fmt.Errorf("grid extent %+v has %dx%d nodes, expected at least 2x2", extent, nRows, nCols)
+	}
+	return nRows, nCols, nil
+}
+
+// ntv2Subgrid is a grid of shifts. Following the NTv2 format, angles are held in
+// arc seconds with longitudes positive west, and nodes are stored row by row from
+// the south east corner.
+type ntv2Subgrid struct {
+	name, parent   string
+	sLat, nLat     float64
+	eLon, wLon     float64
+	latInc, lonInc float64
+	nRows, nCols   int
+	// Latitude shift, longitude shift, latitude accuracy and longitude accuracy of each node
+	nodes    [][4]float32
+	children []*ntv2Subgrid
+}
+
+type ntv2Grid struct {
+	systemFrom, systemTo string
+	subgrids             []*ntv2Subgrid
+	roots                []*ntv2Subgrid
+}
+
+// ntv2Transformer transforms between OSGB36 National Grid and ETRS89 with an NTv2 grid.
+type ntv2Transformer struct {
+	grid *ntv2Grid
+	// toNationalGrid is set when the grid shifts ETRS89 to OSGB36, rather than OSGB36 to ETRS89.
+	toNationalGrid bool
+}
+
+// NewNTv2Transformer returns a transformer that applies the horizontal shifts of an
+// NTv2 grid shift file, such as OSTN15_NTv2_OSGBtoETRS.gsb. The file's SYSTEM_F
+// header decides its direction: grids from ETRS89 shift ETRS89 to OSGB36, others
+// shift OSGB36 to ETRS89. NTv2 has no vertical component, so heights are left unchanged.
+// Positions outside every subgrid, or next to a node with a negative accuracy,
+// return ErrPointOutsideTransformation.
+func NewNTv2Transformer(r io.Reader) (CoordinateTransformer, error) {
+	grid, err := readNTv2(r)
+	if err != nil {
+		return nil, err
+	}
+	return &ntv2Transformer{
+		grid:           grid,
+		toNationalGrid: strings.HasPrefix(strings.ToUpper(grid.systemFrom), "ETRS"),
+	}, nil
+}
+
+func (tr *ntv2Transformer) ToNationalGrid(c *ETRS89Coordinate) (*OSGB36Coordinate, error) {
+	lat, lon, err := tr.shift(c.Lat, c.Lon, tr.toNationalGrid)
+	if err != nil {
+		return nil, err
+	}
+	osgb36Coord := nationalGridAiry.toPlaneCoord(degreesToRadians(lat), degreesToRadians(lon))
+	return &OSGB36Coordinate{
+		Easting:  osgb36Coord.easting,
+		Northing: osgb36Coord.northing,
+		Height:   c.Height,
+	}, nil
+}
+
+func (tr *ntv2Transformer) FromNationalGrid(c *OSGB36Coordinate) (*ETRS89Coordinate, error) {
+	latRadians, lonRadians, err := nationalGridAiry.fromPlaneCoord(&planeCoord{
+		easting:  c.Easting,
+		northing: c.Northing,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lat, lon, err := tr.shift(radiansToDegrees(latRadians), radiansToDegrees(lonRadians), !tr.toNationalGrid)
+	if err != nil {
+		return nil, err
+	}
+	return &ETRS89Coordinate{
+		Lat:    lat,
+		Lon:    lon,
+		Height: c.Height,
+	}, nil
+}
+
+// shift applies the grid shifts to a position in decimal degrees, in the
+// direction of the grid if forward is set, otherwise by inverting it.
+func (tr *ntv2Transformer) shift(lat, lon float64, forward bool) (float64, float64, error) {
+	if forward {
+		return tr.grid.apply(lat, lon)
+	}
+	// Iteratively find the position that shifts onto the target.
+	srcLat, srcLon := lat, lon
+	for iteration := 0; iteration < DefaultMaxIterations; iteration++ {
+		dstLat, dstLon, err := tr.grid.apply(srcLat, srcLon)
+		if err != nil {
+			return 0, 0, err
+		}
+		dLat := lat - dstLat
+		dLon := lon - dstLon
+		srcLat += dLat
+		srcLon += dLon
+		if math.Abs(dLat)*ntv2SecondsPerDegree < ntv2Tolerance && math.Abs(dLon)*ntv2SecondsPerDegree < ntv2Tolerance {
+			return srcLat, srcLon, nil
+		}
+	}
+	return 0, 0, ErrNoConvergence
+}
+
+// apply shifts a position in decimal degrees using the most detailed subgrid containing it.
+func (g *ntv2Grid) apply(lat, lon float64) (float64, float64, error) {
+	latSec := lat * ntv2SecondsPerDegree
+	lonSec := -lon * ntv2SecondsPerDegree
+	sg := findSubgrid(g.roots, latSec, lonSec)
+	if sg == nil {
+		return 0, 0, ErrPointOutsideTransformation
+	}
+	dLat, dLon, err := sg.interpolate(latSec, lonSec)
+	if err != nil {
+		return 0, 0, err
+	}
+	return (latSec + dLat) / ntv2SecondsPerDegree, -(lonSec + dLon) / ntv2SecondsPerDegree, nil
+}
+
+func findSubgrid(subgrids []*ntv2Subgrid, latSec, lonSec float64) *ntv2Subgrid {
+	for _, sg := range subgrids {
+		if !sg.contains(latSec, lonSec) {
+			continue
+		}
+		if child := findSubgrid(sg.children, latSec, lonSec); child != nil {
+			return child
+		}
+		return sg
+	}
+	return nil
+}
+
+func (sg *ntv2Subgrid) contains(latSec, lonSec float64) bool {
+	return latSec >= sg.sLat && latSec <= sg.nLat && lonSec >= sg.eLon && lonSec <= sg.wLon
+}
+
+// interpolate bilinearly interpolates the latitude and longitude shifts in arc seconds.
+func (sg *ntv2Subgrid) interpolate(latSec, lonSec float64) (float64, float64, error) {
+	x := (lonSec - sg.eLon) / sg.lonInc
+	y := (latSec - sg.sLat) / sg.latInc
+	col := int(math.Floor(x))
+	row := int(math.Floor(y))
+	// Positions on the north or west edge use the last cell.
+	if col >= sg.nCols-1 {
+		col = sg.nCols - 2
+	}
+	if row >= sg.nRows-1 {
+		row = sg.nRows - 2
+	}
+	t := x - float64(col)
+	u := y - float64(row)
+
+	n0 := sg.nodes[row*sg.nCols+col]
+	n1 := sg.nodes[row*sg.nCols+col+1]
+	n2 := sg.nodes[(row+1)*sg.nCols+col+1]
+	n3 := sg.nodes[(row+1)*sg.nCols+col]
+	if n0[2] < 0 || n1[2] < 0 || n2[2] < 0 || n3[2] < 0 {
+		return 0, 0, ErrPointOutsideTransformation
+	}
+	dLat := (1-t)*(1-u)*float64(n0[0]) + t*(1-u)*float64(n1[0]) + t*u*float64(n2[0]) + (1-t)*u*float64(n3[0])
+	dLon := (1-t)*(1-u)*float64(n0[1]) + t*(1-u)*float64(n1[1]) + t*u*float64(n2[1]) + (1-t)*u*float64(n3[1])
+	return dLat, dLon, nil
+}
+
+type ntv2Reader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (r *ntv2Reader) record(keyword string) ([]byte, error) {
+	if r.pos+ntv2RecordSize > len(r.data) {
+		return nil, fmt.Errorf("%w: unexpected end of file reading %s", errNTv2Format, keyword)
+	}
+	rec := r.data[r.pos : r.pos+ntv2RecordSize]
+	if key := strings.TrimSpace(string(rec[:8])); key != keyword {
+		return nil, fmt.Errorf("%w: expected %s record at offset %d, found %q", errNTv2Format, keyword, r.pos, key)
+	}
+	r.pos += ntv2RecordSize
+	return rec[8:], nil
+}
+
+func (r *ntv2Reader) int(keyword string) (int, error) {
+	value, err := r.record(keyword)
+	if err != nil {
+		return 0, err
+	}
+	return int(int32(r.order.Uint32(value))), nil
+}
+
+func (r *ntv2Reader) float(keyword string) (float64, error) {
+	value, err := r.record(keyword)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(r.order.Uint64(value)), nil
+}
+
+func (r *ntv2Reader) string(keyword string) (string, error) {
+	value, err := r.record(keyword)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+func readNTv2(rd io.Reader) (*ntv2Grid, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < ntv2RecordSize {
+		return nil, fmt.Errorf("%w: file too short", errNTv2Format)
+	}
+
+	r := &ntv2Reader{data: data, order: binary.LittleEndian}
+	if binary.LittleEndian.Uint32(data[8:12]) != ntv2HeaderRecords {
+		r.order = binary.BigEndian
+	}
+
+	grid := &ntv2Grid{}
+	var nSubgrids int
+	var gsType string
+	for _, field := range []struct {
+		keyword string
+		read    func(string) error
+	}{
+		{"NUM_OREC", func(k string) error {
+			n, err := r.int(k)
+			if err == nil && n != ntv2HeaderRecords {
+				err = fmt.Errorf("%w: unsupported NUM_OREC %d", errNTv2Format, n)
+			}
+			return err
+		}},
+		{"NUM_SREC", func(k string) error {
+			n, err := r.int(k)
+			if err == nil && n != ntv2HeaderRecords {
+				err = fmt.Errorf("%w: unsupported NUM_SREC %d", errNTv2Format, n)
+			}
+			return err
+		}},
+		{"NUM_FILE", func(k string) (err error) { nSubgrids, err = r.int(k); return }},
+		{"GS_TYPE", func(k string) (err error) { gsType, err = r.string(k); return }},
+		{"VERSION", func(k string) (err error) { _, err = r.string(k); return }},
+		{"SYSTEM_F", func(k string) (err error) { grid.systemFrom, err = r.string(k); return }},
+		{"SYSTEM_T", func(k string) (err error) { grid.systemTo, err = r.string(k); return }},
+		{"MAJOR_F", func(k string) (err error) { _, err = r.float(k); return }},
+		{"MINOR_F", func(k string) (err error) { _, err = r.float(k); return }},
+		{"MAJOR_T", func(k string) (err error) { _, err = r.float(k); return }},
+		{"MINOR_T", func(k string) (err error) { _, err = r.float(k); return }},
+	} {
+		if err := field.read(field.keyword); err != nil {
+			return nil, err
+		}
+	}
+	if strings.ToUpper(gsType) != "SECONDS" {
+		return nil, fmt.Errorf("%w: unsupported GS_TYPE %q", errNTv2Format, gsType)
+	}
+
+	byName := map[string]*ntv2Subgrid{}
+	for i := 0; i < nSubgrids; i++ {
+		sg, err := r.subgrid()
+		if err != nil {
+			return nil, err
+		}
+		grid.subgrids = append(grid.subgrids, sg)
+		byName[sg.name] = sg
+	}
+	for _, sg := range grid.subgrids {
+		if strings.ToUpper(sg.parent) == "NONE" {
+			grid.roots = append(grid.roots, sg)
+			continue
+		}
+		parent, ok := byName[sg.parent]
+		if !ok {
+			return nil, fmt.Errorf("%w: subgrid %s has unknown parent %s", errNTv2Format, sg.name, sg.parent)
+		}
+		parent.children = append(parent.children, sg)
+	}
+	return grid, nil
+}
+
+func (r *ntv2Reader) subgrid() (*ntv2Subgrid, error) {
+	sg := &ntv2Subgrid{}
+	var count int
+	var err error
+	if sg.name, err = r.string("SUB_NAME"); err != nil {
+		return nil, err
+	}
+	if sg.parent, err = r.string("PARENT"); err != nil {
+		return nil, err
+	}
+	if _, err = r.string("CREATED"); err != nil {
+		return nil, err
+	}
+	if _, err = r.string("UPDATED"); err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		keyword string
+		value   *float64
+	}{
+		{"S_LAT", &sg.sLat},
+		{"N_LAT", &sg.nLat},
+		{"E_LONG", &sg.eLon},
+		{"W_LONG", &sg.wLon},
+		{"LAT_INC", &sg.latInc},
+		{"LONG_INC", &sg.lonInc},
+	} {
+		if *field.value, err = r.float(field.keyword); err != nil {
+			return nil, err
+		}
+	}
+	if count, err = r.int("GS_COUNT"); err != nil {
+		return nil, err
+	}
+
+	if !(sg.latInc > 0) || !(sg.lonInc > 0) || sg.nLat <= sg.sLat || sg.wLon <= sg.eLon {
+		return nil, fmt.Errorf("%w: subgrid %s has invalid extent", errNTv2Format, sg.name)
+	}
+	sg.nRows = int(math.Floor((sg.nLat-sg.sLat)/sg.latInc+0.5)) + 1
+	sg.nCols = int(math.Floor((sg.wLon-sg.eLon)/sg.lonInc+0.5)) + 1
+	// Interpolation needs a cell of at least 2x2 nodes
+	if sg.nRows < 2 || sg.nCols < 2 {
+		return nil, fmt.Errorf("%w: subgrid %s has %dx%d nodes, expected at least 2x2", errNTv2Format, sg.name, sg.nRows, sg.nCols)
+	}
+	if count != sg.nRows*sg.nCols {
+		return nil, fmt.Errorf("%w: subgrid %s has %d nodes, expected %dx%d", errNTv2Format, sg.name, count, sg.nRows, sg.nCols)
+	}
+	if r.pos+count*ntv2NodeSize > len(r.data) {
+		return nil, fmt.Errorf("%w: unexpected end of file in subgrid %s", errNTv2Format, sg.name)
+	}
+	sg.nodes = make([][4]float32, count)
+	for i := range sg.nodes {
+		for j := range sg.nodes[i] {
+			sg.nodes[i][j] = math.Float32frombits(r.order.Uint32(r.data[r.pos:]))
+			r.pos += 4
+		}
+	}
+	return sg, nil
+}
+
+type ntv2Writer struct {
+	buf bytes.Buffer
+}
+
+func (w *ntv2Writer) keyword(keyword string) {
+	fmt.Fprintf(&w.buf, "%-8s", keyword)
+}
+
+func (w *ntv2Writer) int(keyword string, value int) {
+	w.keyword(keyword)
+	binary.Write(&w.buf, binary.LittleEndian, int32(value))
+	w.buf.Write(make([]byte, 4))
+}
+
+func (w *ntv2Writer) float(keyword string, value float64) {
+	w.keyword(keyword)
+	binary.Write(&w.buf, binary.LittleEndian, value)
+}
+
+func (w *ntv2Writer) string(keyword, value string) {
+	w.keyword(keyword)
+	fmt.Fprintf(&w.buf, "%-8.8s", value)
+}
+
+// writeNTv2 encodes a grid in little endian NTv2 format.
+func writeNTv2(wr io.Writer, grid *ntv2Grid) error {
+	w := &ntv2Writer{}
+	w.int("NUM_OREC", ntv2HeaderRecords)
+	w.int("NUM_SREC", ntv2HeaderRecords)
+	w.int("NUM_FILE", len(grid.subgrids))
+	w.string("GS_TYPE", "SECONDS")
+	w.string("VERSION", "NTv2.0")
+	w.string("SYSTEM_F", grid.systemFrom)
+	w.string("SYSTEM_T", grid.systemTo)
+	from, to := airyEllipsoid, grs80Ellipsoid
+	if strings.HasPrefix(strings.ToUpper(grid.systemFrom), "ETRS") {
+		from, to = to, from
+	}
+	w.float("MAJOR_F", from.semiMajorAxis)
+	w.float("MINOR_F", from.semiMinorAxis)
+	w.float("MAJOR_T", to.semiMajorAxis)
+	w.float("MINOR_T", to.semiMinorAxis)
+
+	for _, sg := range grid.subgrids {
+		w.string("SUB_NAME", sg.name)
+		w.string("PARENT", sg.parent)
+		w.string("CREATED", "")
+		w.string("UPDATED", "")
+		w.float("S_LAT", sg.sLat)
+		w.float("N_LAT", sg.nLat)
+		w.float("E_LONG", sg.eLon)
+		w.float("W_LONG", sg.wLon)
+		w.float("LAT_INC", sg.latInc)
+		w.float("LONG_INC", sg.lonInc)
+		w.int("GS_COUNT", len(sg.nodes))
+		for _, node := range sg.nodes {
+			binary.Write(&w.buf, binary.LittleEndian, node)
+		}
+	}
+	w.keyword("END")
+	w.buf.Write(make([]byte, 8))
+
+	_, err := wr.Write(w.buf.Bytes())
+	return err
+}
+
+// WriteNTv2 samples the horizontal OSGB36 to ETRS89 shifts of a transformer over an
+// extent of OSGB36 latitudes and longitudes, and writes them as a single NTv2 subgrid
+// in the same direction as OSTN15_NTv2_OSGBtoETRS.gsb. Nodes the transformer cannot
+// transform are written with zero shifts and accuracies of -1, which NewNTv2Transformer
+// treats as outside the transformation. Other nodes have zero (unknown) accuracy.
+func WriteNTv2(w io.Writer, tr CoordinateTransformer, extent GeographicExtent) error {
+	nRows, nCols, err := extent.dims()
+	if err != nil {
+		return err
+	}
+	sg := &ntv2Subgrid{
+		name:   "OSTN",
+		parent: "NONE",
+		sLat:   extent.South * ntv2SecondsPerDegree,
+		nLat:   extent.North * ntv2SecondsPerDegree,
+		eLon:   -extent.East * ntv2SecondsPerDegree,
+		wLon:   -extent.West * ntv2SecondsPerDegree,
+		latInc: extent.LatIncrement * ntv2SecondsPerDegree,
+		lonInc: extent.LonIncrement * ntv2SecondsPerDegree,
+		nRows:  nRows,
+		nCols:  nCols,
+	}
+	sg.nodes = make([][4]float32, sg.nRows*sg.nCols)
+
+	for row := 0; row < sg.nRows; row++ {
+		lat := (sg.sLat + float64(row)*sg.latInc) / ntv2SecondsPerDegree
+		for col := 0; col < sg.nCols; col++ {
+			lon := -(sg.eLon + float64(col)*sg.lonInc) / ntv2SecondsPerDegree
+			node := &sg.nodes[row*sg.nCols+col]
+			osgb36Coord := nationalGridAiry.toPlaneCoord(degreesToRadians(lat), degreesToRadians(lon))
+			etrs89Coord, err := tr.FromNationalGrid(&OSGB36Coordinate{
+				Easting:  osgb36Coord.easting,
+				Northing: osgb36Coord.northing,
+			})
+			if err != nil {
+				*node = [4]float32{0, 0, -1, -1}
+				continue
+			}
+			node[0] = float32((etrs89Coord.Lat - lat) * ntv2SecondsPerDegree)
+			node[1] = float32(-(etrs89Coord.Lon - lon) * ntv2SecondsPerDegree)
+		}
+	}
+
+	return writeNTv2(w, &ntv2Grid{
+		systemFrom: "OSGB36",
+		systemTo:   "ETRS89",
+		subgrids:   []*ntv2Subgrid{sg},
+	})
+}
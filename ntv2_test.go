@@ -0,0 +1,176 @@
+package osgb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testNTv2Grid returns a grid with a parent subgrid shifting by one arc second,
+// and a nested child shifting by two.
+func testNTv2Grid() *ntv2Grid {
+	subgrid := func(name, parent string, sLat, nLat, eLon, wLon, shift float64) *ntv2Subgrid {
+		sg := &ntv2Subgrid{
+			name: name, parent: parent,
+			sLat: sLat, nLat: nLat, eLon: eLon, wLon: wLon,
+			latInc: 60, lonInc: 60,
+		}
+		sg.nRows = int((nLat-sLat)/60) + 1
+		sg.nCols = int((wLon-eLon)/60) + 1
+		sg.nodes = make([][4]float32, sg.nRows*sg.nCols)
+		for i := range sg.nodes {
+			sg.nodes[i] = [4]float32{float32(shift), float32(shift), 0, 0}
+		}
+		return sg
+	}
+	parent := subgrid("PARENT", "NONE", 52*3600, 53*3600, -2*3600, -1*3600, 1)
+	child := subgrid("CHILD", "PARENT", 52.5*3600, 52.75*3600, -1.75*3600, -1.5*3600, 2)
+	// Mark the north east node of the parent as outside the transformation
+	parent.nodes[(parent.nRows-1)*parent.nCols] = [4]float32{0, 0, -1, -1}
+	return &ntv2Grid{
+		systemFrom: "OSGB36",
+		systemTo:   "ETRS89",
+		subgrids:   []*ntv2Subgrid{parent, child},
+	}
+}
+
+func TestNTv2Subgrids(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeNTv2(&buf, testNTv2Grid()); err != nil {
+		t.Fatal(err)
+	}
+	grid, err := readNTv2(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grid.roots) != 1 || len(grid.roots[0].children) != 1 {
+		t.Fatalf("expected one root subgrid with one child")
+	}
+
+	testData := []struct {
+		name     string
+		lat, lon float64
+		shift    float64
+		err      error
+	}{
+		{name: "parent", lat: 52.25, lon: 1.25, shift: 1},
+		{name: "child", lat: 52.6, lon: 1.6, shift: 2},
+		{name: "outside", lat: 51.5, lon: 1.5, err: ErrPointOutsideTransformation},
+		{name: "negative accuracy", lat: 52.99, lon: 1.99, err: ErrPointOutsideTransformation},
+	}
+	for _, d := range testData {
+		lat, lon, err := grid.apply(d.lat, d.lon)
+		if err != d.err {
+			t.Errorf("%s: expected error %v, actual %v", d.name, d.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		// Longitude shifts are positive west
+		if math.Abs((lat-d.lat)*3600-d.shift) > 1e-9 || math.Abs((d.lon-lon)*3600-d.shift) > 1e-9 {
+			t.Errorf("%s: expected shift of %f\", actual %f\", %f\"", d.name, d.shift, (lat-d.lat)*3600, (d.lon-lon)*3600)
+		}
+	}
+}
+
+func TestNTv2Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeNTv2(&buf, testNTv2Grid()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readNTv2(bytes.NewReader(buf.Bytes()[:buf.Len()/2])); !errors.Is(err, errNTv2Format) {
+		t.Errorf("expected %v for truncated grid, actual %v", errNTv2Format, err)
+	}
+	if _, err := readNTv2(bytes.NewReader(buf.Bytes()[ntv2RecordSize:])); !errors.Is(err, errNTv2Format) {
+		t.Errorf("expected %v for missing header record, actual %v", errNTv2Format, err)
+	}
+
+	// A subgrid narrower than half its latitude increment has a single row
+	// of nodes and no cells to interpolate.
+	grid := testNTv2Grid()
+	grid.subgrids = grid.subgrids[:1]
+	sg := grid.subgrids[0]
+	sg.nLat = sg.sLat + sg.latInc/4
+	sg.nRows = 1
+	sg.nodes = sg.nodes[:sg.nCols]
+	buf.Reset()
+	if err := writeNTv2(&buf, grid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readNTv2(&buf); !errors.Is(err, errNTv2Format) {
+		t.Errorf("expected %v for subgrid with one row, actual %v", errNTv2Format, err)
+	}
+}
+
+func TestNTv2Ellipsoids(t *testing.T) {
+	for _, test := range []struct {
+		systemFrom string
+		from, to   *ellipsoid
+	}{
+		{"OSGB36", airyEllipsoid, grs80Ellipsoid},
+		{"ETRS89", grs80Ellipsoid, airyEllipsoid},
+	} {
+		grid := testNTv2Grid()
+		grid.systemFrom = test.systemFrom
+		var buf bytes.Buffer
+		if err := writeNTv2(&buf, grid); err != nil {
+			t.Fatal(err)
+		}
+		// MAJOR_F to MINOR_T are the 8th to 11th header records.
+		header := buf.Bytes()
+		for i, expected := range []float64{test.from.semiMajorAxis, test.from.semiMinorAxis, test.to.semiMajorAxis, test.to.semiMinorAxis} {
+			record := header[(7+i)*ntv2RecordSize : (8+i)*ntv2RecordSize]
+			actual := math.Float64frombits(binary.LittleEndian.Uint64(record[8:]))
+			if actual != expected {
+				t.Errorf("%s: expected %s %f, actual %f", test.systemFrom, strings.TrimSpace(string(record[:8])), expected, actual)
+			}
+		}
+		if _, err := readNTv2(&buf); err != nil {
+			t.Errorf("%s: %v", test.systemFrom, err)
+		}
+	}
+}
+
+func TestNTv2ExportOSTN15(t *testing.T) {
+	ostn15, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	extent := GeographicExtent{
+		South: 52.6, North: 52.7,
+		West: 1.6, East: 1.8,
+		LatIncrement: DefaultGeographicExtent.LatIncrement,
+		LonIncrement: DefaultGeographicExtent.LonIncrement,
+	}
+	if err := WriteNTv2(&buf, ostn15, extent); err != nil {
+		t.Fatal(err)
+	}
+	ntv2, err := NewNTv2Transformer(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	osgb36Coord := &OSGB36Coordinate{Easting: 651409.792, Northing: 313177.448}
+	expected, err := ostn15.FromNationalGrid(osgb36Coord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ntv2.FromNationalGrid(osgb36Coord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAngle(t, "Lat", expected.Lat, actual.Lat)
+	checkAngle(t, "Lon", expected.Lon, actual.Lon)
+
+	roundTrip, err := ntv2.ToNationalGrid(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "Easting", osgb36Coord.Easting, roundTrip.Easting)
+	checkDistance(t, "Northing", osgb36Coord.Northing, roundTrip.Northing)
+}
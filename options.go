@@ -0,0 +1,150 @@
+package osgb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultTolerance is the convergence tolerance in metres used when
+	// transforming from OSGB36/ODN to ETRS89.
+	DefaultTolerance = 0.0001
+	// DefaultMaxIterations is the maximum number of iterations used when
+	// transforming from OSGB36/ODN to ETRS89.
+	DefaultMaxIterations = 20
+)
+
+// OffshorePolicy controls how a transformer handles positions in offshore grid cells.
+type OffshorePolicy int
+
+const (
+	// OffshoreTransform transforms offshore positions wherever the grid
+	// defines shifts for them. This is the default.
+	OffshoreTransform OffshorePolicy = iota
+	// OffshoreReject returns ErrPointOffshore for positions that lie in a
+	// grid cell with an offshore corner, and so also for positions whose
+	// nearest geoid region is offshore.
+	OffshoreReject
+	// OffshoreFallback transforms positions the grid cannot transform onshore,
+	// those in offshore grid cells, outside the OSTN02 polygon or beyond the grid,
+	// with the OS Helmert transformation between ETRS89 and OSGB36 instead. It is
+	// accurate to around 5m. It cannot relate ODN and ellipsoidal heights, so
+	// fallback results have NaN heights and no VerticalDatum. The fallback applies to
+	// ToNationalGrid, FromNationalGrid, their Value and batch forms and
+	// FromNationalGridWithDiagnostics; other methods treat offshore cells as
+	// OffshoreReject does. Positions with a NaN or infinite longitude, latitude,
+	// easting or northing never fall back.
+	OffshoreFallback
+)
+
+// Option configures a transformer returned by NewOSTN02Transformer or NewOSTN15Transformer.
+type Option func(*GridTransformer) error
+
+// WithTolerance sets the convergence tolerance in metres of the iterative
+// OSGB36/ODN to ETRS89 transformation.
+func WithTolerance(metres float64) Option {
+	return func(tr *GridTransformer) error {
+		if !(metres > 0) {
+			return fmt.Errorf("invalid tolerance %f", metres)
+		}
+		tr.tolerance = metres
+		return nil
+	}
+}
+
+// WithMaxIterations sets the maximum number of iterations of the OSGB36/ODN to ETRS89
+// transformation before ErrNoConvergence is returned.
+func WithMaxIterations(n int) Option {
+	return func(tr *GridTransformer) error {
+		if n < 1 {
+			return fmt.Errorf("invalid maximum iterations %d", n)
+		}
+		tr.maxIterations = n
+		return nil
+	}
+}
+
+// WithOffshorePolicy sets how positions in offshore grid cells are handled.
+func WithOffshorePolicy(policy OffshorePolicy) Option {
+	return func(tr *GridTransformer) error {
+		if policy != OffshoreTransform && policy != OffshoreReject && policy != OffshoreFallback {
+			return fmt.Errorf("invalid offshore policy %d", policy)
+		}
+		tr.offshorePolicy = policy
+		return nil
+	}
+}
+
+// WithGrid replaces the built-in transformation grid with one read from r,
+// which must be in the OS translation vector format: a header line followed by
+// the 701x1251 grid records.
+func WithGrid(r io.Reader) Option {
+	return func(tr *GridTransformer) error {
+		h := sha256.New()
+		records, err := parseRecords(io.TeeReader(r, h))
+		if err != nil {
+			return err
+		}
+		tr.records = records
+		tr.gridSum = hex.EncodeToString(h.Sum(nil))
+		return nil
+	}
+}
+
+// WithStrictOnshore rejects offshore positions with ErrPointOffshore, giving OSTN15
+// the same onshore-only behaviour as OSTN02. It is equivalent to
+// WithOffshorePolicy(OffshoreReject).
+func WithStrictOnshore() Option {
+	return WithOffshorePolicy(OffshoreReject)
+}
+
+// WithInterpolation sets how shifts are interpolated between grid records.
+// The default, InterpolationBilinear, is the OS defined method.
+func WithInterpolation(interpolation Interpolation) Option {
+	return func(tr *GridTransformer) error {
+		switch interpolation {
+		case InterpolationBilinear, InterpolationBicubic, InterpolationBiquadratic:
+			tr.interpolation = interpolation
+			return nil
+		}
+		return fmt.Errorf("invalid interpolation %d", interpolation)
+	}
+}
+
+// WithInverseGrid precomputes, on first use, a grid of ETRS89 shifts indexed by OSGB36
+// easting and northing. Transformations from OSGB36 then start from an interpolated
+// estimate and converge in one or two steps, rather than iterating from the OSGB36
+// position. The grid takes around 10MB and a few seconds to build.
+func WithInverseGrid() Option {
+	return func(tr *GridTransformer) error {
+		tr.useInverseGrid = true
+		return nil
+	}
+}
+
+// WithCellCache keeps the records and bilinear shift coefficients of the last few dozen
+// grid cells used, so runs of positions in the same 1km cell, as in GPS tracks, skip the
+// grid lookup. The cache is shared by all goroutines using the transformer and is safe
+// for concurrent use. It is only used with bilinear interpolation.
+func WithCellCache() Option {
+	return func(tr *GridTransformer) error {
+		tr.cells = &cellCache{}
+		return nil
+	}
+}
+
+// WithChecksum sets the expected checksum of the grid, as reported by Metadata, so a
+// grid loaded with WithGrid or from a grid file is checked when the transformer is
+// created, returning ErrChecksumMismatch if it differs. Verify also checks against it.
+func WithChecksum(sha256 string) Option {
+	return func(tr *GridTransformer) error {
+		sum, err := hex.DecodeString(sha256)
+		if err != nil || len(sum) != 32 {
+			return fmt.Errorf("invalid SHA-256 checksum %q", sha256)
+		}
+		tr.expectedChecksum = hex.EncodeToString(sum)
+		return nil
+	}
+}
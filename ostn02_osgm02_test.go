@@ -88,7 +88,7 @@ type ostn02TestOutput struct {
 	osgb36Lat      float64
 	osgb36Lon      float64
 	odnHeight      float64
-	geoidModelID   geoidRegion
+	geoidModelID   GeoidRegion
 }
 
 func read02OutputData() (map[string]ostn02TestOutput, error) {
@@ -249,6 +249,10 @@ func read02OutputData() (map[string]ostn02TestOutput, error) {
 		if err != nil {
 			return nil, err
 		}
+		geoidModelRegion, err := geoidDatumToRegion(geoidModelID)
+		if err != nil {
+			return nil, err
+		}
 
 		outputData[stationName] = ostn02TestOutput{
 			stationName:    stationName,
@@ -265,7 +269,7 @@ func read02OutputData() (map[string]ostn02TestOutput, error) {
 			osgb36Lat:      osgb36Lat,
 			osgb36Lon:      osgb36Lon,
 			odnHeight:      odnHeight,
-			geoidModelID:   geoidDatumToRegion(geoidModelID),
+			geoidModelID:   geoidModelRegion,
 		}
 	}
 	log.Println("Reading ostn02_osgm02 test output data completed...")
@@ -298,11 +302,14 @@ func Test02Data(t *testing.T) {
 		checkDistance(t, "etrs89 y", output.etrs89Y, input.etrs89Y)
 		checkDistance(t, "etrs89 z", output.etrs89Z, input.etrs89Z)
 
-		geoCoord := grs80Ellipsoid.cartesianToGeographic(&cartesianCoord{
+		geoCoord, err := grs80Ellipsoid.cartesianToGeographic(&cartesianCoord{
 			x: input.etrs89X,
 			y: input.etrs89Y,
 			z: input.etrs89Z,
 		})
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		latDegrees := radiansToDegrees(geoCoord.lat)
 		lonDegrees := radiansToDegrees(geoCoord.lon)
@@ -311,7 +318,7 @@ func Test02Data(t *testing.T) {
 		checkAngle(t, "etrs89 longitude", output.etrs89Lon, lonDegrees)
 		checkDistance(t, "etrs89 height", output.etrs89Height, geoCoord.height)
 
-		etrs89Coord := nationalGridProjection.toPlaneCoord(geoCoord.lat, geoCoord.lon, grs80Ellipsoid)
+		etrs89Coord := nationalGridGRS80.toPlaneCoord(geoCoord.lat, geoCoord.lon)
 
 		checkDistance(t, "etrs89 east", output.etrs89Easting, etrs89Coord.easting)
 		checkDistance(t, "etrs89 north", output.etrs89Northing, etrs89Coord.northing)
@@ -343,10 +350,14 @@ func Test02Data(t *testing.T) {
 		checkDistance(t, "osgb36 north", output.osgb36Northing, osgb36Coord.Northing)
 		checkDistance(t, "orthometric height", output.odnHeight, osgb36Coord.Height)
 
-		osgb36Lat, osgb36Lon := nationalGridProjection.fromPlaneCoord(&planeCoord{
+		osgb36Lat, osgb36Lon, err := nationalGridAiry.fromPlaneCoord(&planeCoord{
 			easting:  osgb36Coord.Easting,
 			northing: osgb36Coord.Northing,
-		}, airyEllipsoid)
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for station %s: %s", station, err)
+			continue
+		}
 
 		osgb36LatDegrees := radiansToDegrees(osgb36Lat)
 		osgb36LonDegrees := radiansToDegrees(osgb36Lon)
@@ -423,6 +434,20 @@ func Test02OSGB36ToETRS89(t *testing.T) {
 	checkDistance(t, "etrs89 height", expectedETRS89Height, etrs89Coord.Height)
 }
 
+// The embedded grid matches the data file published by OS.
+func Test02Verify(t *testing.T) {
+	trans, err := NewOSTN02Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ostn02Model.checksum == "" {
+		t.Skip("no checksum is recorded for the published OSTN02_OSGM02_GB.txt")
+	}
+	if err := trans.Verify(); err != nil {
+		t.Errorf("expected embedded grid to verify, actual %v (checksum %s)", err, trans.Metadata().Checksum)
+	}
+}
+
 func Test02ETRS89ToOSGB36_OutsideTransformationRange(t *testing.T) {
 	etrs89Lat := 0.0
 	etrs89Lon := 0.0
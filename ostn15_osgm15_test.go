@@ -1,12 +1,17 @@
 package osgb
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"io"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/mjjbell/go-osgb/internal/data"
 )
 
 type ostn15OSGBToETRSTestInput struct {
@@ -137,7 +142,8 @@ type ostn15ETRSToOSGBTestOutput struct {
 	osgb36Easting  float64
 	osgb36Northing float64
 	odnHeight      float64
-	geoidModelID   geoidRegion
+	geoidModelID   GeoidRegion
+	shifts         GridShifts
 }
 
 type ostn15OSGBToETRSTestOutput struct {
@@ -190,13 +196,23 @@ func read15ETRSToOSGBOutputData() (map[string]ostn15ETRSToOSGBTestOutput, error)
 		if err != nil {
 			return nil, err
 		}
+		geoidModelRegion, err := geoidDatumToRegion(geoidModelID)
+		if err != nil {
+			return nil, err
+		}
+
+		shifts, err := parse15GridShifts(record)
+		if err != nil {
+			return nil, err
+		}
 
 		outputData[pointID] = ostn15ETRSToOSGBTestOutput{
 			pointID:        pointID,
 			osgb36Easting:  osgb36Easting,
 			osgb36Northing: osgb36Northing,
 			odnHeight:      odnHeight,
-			geoidModelID:   geoidDatumToRegion(geoidModelID),
+			geoidModelID:   geoidModelRegion,
+			shifts:         *shifts,
 		}
 	}
 	log.Println("Reading ostn15_osgm15 test output data completed...")
@@ -204,6 +220,33 @@ func read15ETRSToOSGBOutputData() (map[string]ostn15ETRSToOSGBTestOutput, error)
 	return outputData, nil
 }
 
+// parse15GridShifts reads the RecNoS0 to Sg columns of an ETRS to OSGB test output record.
+func parse15GridShifts(record []string) (*GridShifts, error) {
+	shifts := &GridShifts{}
+	values := make([]float64, 23)
+	for i := range values {
+		v, err := strconv.ParseFloat(record[i+5], 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	for i := range shifts.Records {
+		v := values[i*5 : i*5+5]
+		shifts.Records[i] = ShiftRecord{
+			RecordNo:    uint32(v[0]),
+			EastShift:   v[1],
+			NorthShift:  v[2],
+			GeoidHeight: v[3],
+			Region:      GeoidRegion(v[4]),
+		}
+	}
+	shifts.EastShift = values[20]
+	shifts.NorthShift = values[21]
+	shifts.GeoidHeight = values[22]
+	return shifts, nil
+}
+
 func read15OSGBToETRSOutputData() (map[string]ostn15OSGBToETRSTestOutput, error) {
 
 	outputData := map[string]ostn15OSGBToETRSTestOutput{}
@@ -259,6 +302,58 @@ func read15OSGBToETRSOutputData() (map[string]ostn15OSGBToETRSTestOutput, error)
 	return outputData, nil
 }
 
+func read15OSGBToETRSIterationData() (map[string][]IterationStep, error) {
+
+	iterationData := map[string][]IterationStep{}
+
+	f, err := os.Open(test15OSGBToETRSOutputFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	// Read header
+	if _, err := r.Read(); err != nil {
+		return nil, err
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if iteration := record[1]; iteration == "RESULT" {
+			continue
+		}
+
+		pointID := record[0]
+		etrs89Easting, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, err
+		}
+		etrs89Northing, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, err
+		}
+		etrs89Height, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		iterationData[pointID] = append(iterationData[pointID], IterationStep{
+			Easting:  etrs89Easting,
+			Northing: etrs89Northing,
+			Height:   etrs89Height,
+		})
+	}
+
+	return iterationData, nil
+}
+
 func Test15ETRS89ToOSGB36Data(t *testing.T) {
 	inputs, err := read15ETRSToOSGBInputData()
 	if err != nil {
@@ -461,3 +556,239 @@ func Test15OSGB36ToETRS89_OutsideTransformationRange(t *testing.T) {
 		t.Errorf("expected error when converting osgb36 coords outside ostn15 transformation range")
 	}
 }
+
+func Test15OSGB36ToETRS89Diagnostics(t *testing.T) {
+	inputs, err := read15OSGBToETRSInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iterations, err := read15OSGBToETRSIterationData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pointID, input := range inputs {
+		steps, ok := iterations[pointID]
+		if !ok {
+			continue
+		}
+
+		_, diag, err := trans.FromNationalGridWithDiagnostics(&OSGB36Coordinate{
+			Easting:  input.osgbEasting,
+			Northing: input.osgbNorthing,
+			Height:   input.orthometricHeight,
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+
+		if diag.Iterations != len(steps) || len(diag.Steps) != len(steps) {
+			t.Errorf("point ID %s: expected %d iterations, actual %d", pointID, len(steps), diag.Iterations)
+			continue
+		}
+		for i, step := range steps {
+			// The OS output is rounded to 0.1mm
+			checkDistance(t, "iteration easting", step.Easting, diag.Steps[i].Easting)
+			checkDistance(t, "iteration northing", step.Northing, diag.Steps[i].Northing)
+			checkDistance(t, "iteration height", step.Height, diag.Steps[i].Height)
+		}
+		if diag.EastingResidual > DefaultTolerance ||
+			diag.NorthingResidual > DefaultTolerance ||
+			diag.HeightResidual > DefaultTolerance {
+			t.Errorf("point ID %s: residuals exceed tolerance: %#v", pointID, diag)
+		}
+	}
+}
+
+func Test15OSGB36ToETRS89_NoConvergence(t *testing.T) {
+	trans, err := NewOSTN15Transformer(WithMaxIterations(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, diag, err := trans.FromNationalGridWithDiagnostics(&OSGB36Coordinate{
+		Easting:  651409.804,
+		Northing: 313177.450,
+		Height:   63.822,
+	})
+	if err != ErrNoConvergence {
+		t.Fatalf("expected ErrNoConvergence, actual %v", err)
+	}
+	if diag.Iterations != 1 {
+		t.Errorf("expected 1 iteration, actual %d", diag.Iterations)
+	}
+}
+
+func Test15InvalidOptions(t *testing.T) {
+	for _, opt := range []Option{WithTolerance(0), WithTolerance(-1), WithMaxIterations(0),
+		WithOffshorePolicy(OffshorePolicy(-1)), WithInterpolation(Interpolation(-1)), WithChecksum("0123")} {
+		if _, err := NewOSTN15Transformer(opt); err == nil {
+			t.Errorf("expected error for invalid option")
+		}
+	}
+}
+
+func Test15Metadata(t *testing.T) {
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := trans.Metadata()
+	if md.Model != "OSTN/OSGM" || md.Version != "15" || md.Records != 876951 ||
+		md.MinEasting != 0 || md.MinNorthing != 0 || md.MaxEasting != 700000 || md.MaxNorthing != 1250000 {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+
+	// The same grid loaded from the translation vector file
+	grid, err := data.Asset(translationVectorFile15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := NewOSTN15Transformer(WithGrid(bytes.NewReader(grid)), WithChecksum(md.Checksum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customMD := custom.Metadata(); customMD.Model != "" || customMD.Checksum != md.Checksum {
+		t.Errorf("expected custom grid with checksum %s, actual %+v", md.Checksum, customMD)
+	}
+	if _, err := NewOSTN15Transformer(WithGrid(bytes.NewReader(grid)), WithChecksum(strings.Repeat("0", 64))); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v, actual %v", ErrChecksumMismatch, err)
+	}
+}
+
+// The embedded grid matches the data file published by OS.
+func Test15Verify(t *testing.T) {
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ostn15Model.checksum == "" {
+		t.Skip("no checksum is recorded for the published OSTN15_OSGM15_DataFile.txt")
+	}
+	if err := trans.Verify(); err != nil {
+		t.Errorf("expected embedded grid to verify, actual %v (checksum %s)", err, trans.Metadata().Checksum)
+	}
+}
+
+func Test15ETRS89ToOSGB36_OffshorePolicy(t *testing.T) {
+	// TP31 lies in an offshore grid cell near St Kilda.
+	tp31 := &ETRS89Coordinate{
+		Lat:    57.81351838410,
+		Lon:    -8.57854456076,
+		Height: 100.001,
+	}
+
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	osgb36Coord, err := trans.ToNationalGrid(tp31)
+	if err != nil {
+		t.Fatalf("failed to convert offshore etrs89 to osgb36/odn: %s", err)
+	}
+	checkDistance(t, "national grid east", 9587.909, osgb36Coord.Easting)
+	checkDistance(t, "national grid north", 899448.996, osgb36Coord.Northing)
+
+	for _, opt := range []Option{WithOffshorePolicy(OffshoreReject), WithStrictOnshore()} {
+		strict, err := NewOSTN15Transformer(opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := strict.ToNationalGrid(tp31); err != ErrPointOffshore {
+			t.Errorf("expected ErrPointOffshore, actual %v", err)
+		}
+		if _, err := strict.FromNationalGrid(osgb36Coord); err != ErrPointOffshore {
+			t.Errorf("expected ErrPointOffshore, actual %v", err)
+		}
+	}
+}
+
+func Test15StrictOnshoreData(t *testing.T) {
+	inputs, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := NewOSTN15Transformer(WithStrictOnshore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test points whose grid cell has an offshore corner, from the datum
+	// flags of the corners in the OS test output.
+	offshorePoints := map[string]bool{
+		"TP31": true,
+		"TP32": true,
+		"TP36": true,
+		"TP37": true,
+		"TP38": true,
+		"TP40": true,
+	}
+
+	for pointID, input := range inputs {
+		output, ok := outputs[pointID]
+		if !ok {
+			t.Fatal("missing point ID in output ", pointID)
+		}
+
+		osgb36Coord, err := trans.ToNationalGrid(&ETRS89Coordinate{
+			Lat:    input.etrs89Lat,
+			Lon:    input.etrs89Lon,
+			Height: input.etrs89Height,
+		})
+		if offshorePoints[pointID] {
+			if err != ErrPointOffshore {
+				t.Errorf("point ID %s: expected ErrPointOffshore, actual %v", pointID, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+
+		checkDistance(t, "osgb36 east", output.osgb36Easting, osgb36Coord.Easting)
+		checkDistance(t, "osgb36 north", output.osgb36Northing, osgb36Coord.Northing)
+		checkDistance(t, "orthometric height", output.odnHeight, osgb36Coord.Height)
+	}
+}
+
+func Test15WithGrid(t *testing.T) {
+	grid, err := data.Asset(translationVectorFile15)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := NewOSTN02Transformer(WithGrid(bytes.NewReader(grid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	osgb36Coord, err := trans.ToNationalGrid(&ETRS89Coordinate{
+		Lat:    52.65800783333,
+		Lon:    1.71607397222,
+		Height: 108.05,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OSTN15 result, not OSTN02
+	checkDistance(t, "national grid east", 651409.804, osgb36Coord.Easting)
+	checkDistance(t, "national grid north", 313177.450, osgb36Coord.Northing)
+
+	if _, err := NewOSTN15Transformer(WithGrid(bytes.NewReader(grid[:1000]))); err == nil {
+		t.Errorf("expected error for truncated grid")
+	}
+}
@@ -2,6 +2,7 @@ package osgb
 
 import (
 	"errors"
+	"fmt"
 	"math"
 )
 
@@ -10,36 +11,76 @@ var (
 	ErrPointOutsidePolygon = errors.New("point outside polygon")
 	// ErrPointOutsideTransformation indicates the position is completely outside the grid transformation extent
 	ErrPointOutsideTransformation = errors.New("point outside transformation limits")
+	// ErrPointOffshore indicates the position lies in an offshore grid cell and the
+	// transformer was configured to reject offshore positions.
+	ErrPointOffshore = errors.New("point offshore")
+	// ErrPointOutsideDatum indicates the position is outside the region of the requested vertical datum.
+	ErrPointOutsideDatum = errors.New("point outside vertical datum region")
+	// ErrNoConvergence indicates an iterative transformation did not reach
+	// the required tolerance within the maximum number of iterations.
+	ErrNoConvergence = errors.New("transformation did not converge")
+	// ErrNonFinite indicates a coordinate is NaN or infinite.
+	ErrNonFinite = errors.New("coordinate is not finite")
 )
 
 const (
 	nEastIndices            = 701
+	nNorthIndices           = 1251
 	translationVectorFile02 = "data/OSTN02_OSGM02_GB.txt"
 	translationVectorFile15 = "data/OSTN15_OSGM15_GB.txt"
 )
 
-type geoidRegion uint8
+// GeoidRegion identifies the geoid model region, and so the vertical datum,
+// of a grid record.
+type GeoidRegion uint8
 
 const (
-	Region_OUTSIDE_BOUNDARY       geoidRegion = 0  // 02
-	Region_UK_MAINLAND            geoidRegion = 1  // 02,15
-	Region_SCILLY_ISLES           geoidRegion = 2  // 02,15
-	Region_ISLE_OF_MAN            geoidRegion = 3  // 02,15
-	Region_OUTER_HEBRIDES         geoidRegion = 4  // 02,15
-	Region_ST_KILDA               geoidRegion = 5  // 02
-	Region_SHETLAND_ISLES         geoidRegion = 6  // 02,15
-	Region_ORKNEY_ISLES           geoidRegion = 7  // 02,15
-	Region_FAIR_ISLE              geoidRegion = 8  // 02
-	Region_FLANNAN_ISLES          geoidRegion = 9  // 02
-	Region_NORTH_RONA             geoidRegion = 10 // 02
-	Region_SULE_SKERRY            geoidRegion = 11 // 02
-	Region_FOULA                  geoidRegion = 12 // 02
-	Region_REPUBLIC_OF_IRELAND    geoidRegion = 13 // 02
-	Region_NORTHERN_IRELAND       geoidRegion = 14 // 02
-	Region_OFFSHORE               geoidRegion = 15 // 15
-	Region_OUTSIDE_TRANSFORMATION geoidRegion = 16 // 15
+	Region_OUTSIDE_BOUNDARY       GeoidRegion = 0  // 02
+	Region_UK_MAINLAND            GeoidRegion = 1  // 02,15
+	Region_SCILLY_ISLES           GeoidRegion = 2  // 02,15
+	Region_ISLE_OF_MAN            GeoidRegion = 3  // 02,15
+	Region_OUTER_HEBRIDES         GeoidRegion = 4  // 02,15
+	Region_ST_KILDA               GeoidRegion = 5  // 02
+	Region_SHETLAND_ISLES         GeoidRegion = 6  // 02,15
+	Region_ORKNEY_ISLES           GeoidRegion = 7  // 02,15
+	Region_FAIR_ISLE              GeoidRegion = 8  // 02
+	Region_FLANNAN_ISLES          GeoidRegion = 9  // 02
+	Region_NORTH_RONA             GeoidRegion = 10 // 02
+	Region_SULE_SKERRY            GeoidRegion = 11 // 02
+	Region_FOULA                  GeoidRegion = 12 // 02
+	Region_REPUBLIC_OF_IRELAND    GeoidRegion = 13 // 02
+	Region_NORTHERN_IRELAND       GeoidRegion = 14 // 02
+	Region_OFFSHORE               GeoidRegion = 15 // 15
+	Region_OUTSIDE_TRANSFORMATION GeoidRegion = 16 // 15
 )
 
+var geoidRegionNames = map[GeoidRegion]string{
+	Region_OUTSIDE_BOUNDARY:       "Outside boundary",
+	Region_UK_MAINLAND:            "UK mainland",
+	Region_SCILLY_ISLES:           "Scilly Isles",
+	Region_ISLE_OF_MAN:            "Isle of Man",
+	Region_OUTER_HEBRIDES:         "Outer Hebrides",
+	Region_ST_KILDA:               "St Kilda",
+	Region_SHETLAND_ISLES:         "Shetland Isles",
+	Region_ORKNEY_ISLES:           "Orkney Isles",
+	Region_FAIR_ISLE:              "Fair Isle",
+	Region_FLANNAN_ISLES:          "Flannan Isles",
+	Region_NORTH_RONA:             "North Rona",
+	Region_SULE_SKERRY:            "Sule Skerry",
+	Region_FOULA:                  "Foula",
+	Region_REPUBLIC_OF_IRELAND:    "Republic of Ireland",
+	Region_NORTHERN_IRELAND:       "Northern Ireland",
+	Region_OFFSHORE:               "Offshore",
+	Region_OUTSIDE_TRANSFORMATION: "Outside transformation",
+}
+
+func (r GeoidRegion) String() string {
+	if name, ok := geoidRegionNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("GeoidRegion(%d)", uint8(r))
+}
+
 // CoordinateTransformer is used to convert between OSGB36/ODN and ETRS89 geodetic datums
 type CoordinateTransformer interface {
 	// ToNationalGrid coverts a coordinate position from ETRS89 to OSGB36/ODN
@@ -48,49 +89,163 @@ type CoordinateTransformer interface {
 	FromNationalGrid(c *OSGB36Coordinate) (*ETRS89Coordinate, error)
 }
 
-type transformer struct {
-	records []record
+// Diagnostics describes how the iterative OSGB36/ODN to ETRS89 transformation converged.
+type Diagnostics struct {
+	// Iterations is the number of grid shift iterations performed.
+	Iterations int
+	// Steps holds the ETRS89 estimate computed by each iteration,
+	// as listed in the "Iteration No." rows of the OS test output.
+	Steps []IterationStep
+	// EastingResidual is the change in ETRS89 easting made by the final iteration, in metres.
+	EastingResidual float64
+	// NorthingResidual is the change in ETRS89 northing made by the final iteration, in metres.
+	NorthingResidual float64
+	// HeightResidual is the change in ETRS89 height made by the final iteration, in metres.
+	HeightResidual float64
 }
 
-func (tr *transformer) ToNationalGrid(c *ETRS89Coordinate) (*OSGB36Coordinate, error) {
-	latRadians := degreesToRadians(c.Lat)
-	lonRadians := degreesToRadians(c.Lon)
-	etrs89PlaneCoord := nationalGridProjection.toPlaneCoord(latRadians, lonRadians, grs80Ellipsoid)
-	osgb36Coord, odnHeight, _, err := tr.toOSGB36(&planeCoord{
-		easting:  etrs89PlaneCoord.easting,
-		northing: etrs89PlaneCoord.northing,
-	}, c.Height)
+// IterationStep is the ETRS89 estimate computed by one iteration of the
+// OSGB36/ODN to ETRS89 transformation.
+type IterationStep struct {
+	// Easting in metres
+	Easting float64
+	// Northing in metres
+	Northing float64
+	// Height in metres
+	Height float64
+}
+
+// GridTransformer converts between OSGB36/ODN and ETRS89 with an OSTN/OSGM
+// transformation grid. It is safe for concurrent use.
+type GridTransformer struct {
+	records        []record
+	tolerance      float64
+	maxIterations  int
+	offshorePolicy OffshorePolicy
+	interpolation  Interpolation
+	useInverseGrid bool
+	inverse        inverseGrid
+	cells          *cellCache
+	mapped         *mappedGrid
+	// model is the OS release the grid was built from, nil for custom grids
+	model *gridModel
+	// asset is the embedded translation vector file of the built-in grid
+	asset string
+	// gridSum is the checksum of the translation vector file read with WithGrid
+	gridSum          string
+	expectedChecksum string
+	sum              gridChecksum
+}
+
+// ToNationalGrid coverts a coordinate position from ETRS89 to OSGB36/ODN
+func (tr *GridTransformer) ToNationalGrid(c *ETRS89Coordinate) (*OSGB36Coordinate, error) {
+	osgb36Coord, err := tr.ToNationalGridValue(*c)
 	if err != nil {
 		return nil, err
 	}
-	return &OSGB36Coordinate{
-		Easting:  osgb36Coord.easting,
-		Northing: osgb36Coord.northing,
-		Height:   odnHeight,
+	return &osgb36Coord, nil
+}
+
+// ToNationalGridValue coverts a coordinate position from ETRS89 to OSGB36/ODN as
+// ToNationalGrid, passing coordinates by value so the conversion does not allocate.
+func (tr *GridTransformer) ToNationalGridValue(c ETRS89Coordinate) (OSGB36Coordinate, error) {
+	etrs89Coord := etrs89ToPlaneCoord(&c)
+	osgb36Coord, odnHeight, region, err := tr.toOSGB36(&etrs89Coord, c.Height)
+	if tr.fallsBack(err, c.Lon, c.Lat) {
+		return helmertToNationalGrid(&c)
+	}
+	if err != nil {
+		return OSGB36Coordinate{}, err
+	}
+	return OSGB36Coordinate{
+		Easting:       osgb36Coord.easting,
+		Northing:      osgb36Coord.northing,
+		Height:        odnHeight,
+		VerticalDatum: regionVerticalDatums[region],
 	}, nil
 }
 
-func (tr *transformer) FromNationalGrid(c *OSGB36Coordinate) (*ETRS89Coordinate, error) {
+// FromNationalGrid coverts a coordinate position from OSGB36/ODN to ETRS89
+func (tr *GridTransformer) FromNationalGrid(c *OSGB36Coordinate) (*ETRS89Coordinate, error) {
+	etrs89Coord, err := tr.fromNationalGrid(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &etrs89Coord, nil
+}
+
+// FromNationalGridValue coverts a coordinate position from OSGB36/ODN to ETRS89 as
+// FromNationalGrid, passing coordinates by value so the conversion does not allocate.
+func (tr *GridTransformer) FromNationalGridValue(c OSGB36Coordinate) (ETRS89Coordinate, error) {
+	return tr.fromNationalGrid(&c, nil)
+}
+
+// FromNationalGridWithDiagnostics coverts a coordinate position from OSGB36/ODN to ETRS89,
+// also reporting how the iterative transformation converged. The diagnostics
+// are returned alongside ErrNoConvergence so the failed iterations can be inspected.
+func (tr *GridTransformer) FromNationalGridWithDiagnostics(c *OSGB36Coordinate) (*ETRS89Coordinate, *Diagnostics, error) {
+	diag := &Diagnostics{}
+	etrs89Coord, err := tr.fromNationalGrid(c, diag)
+	if err != nil {
+		return nil, diag, err
+	}
+	return &etrs89Coord, diag, nil
+}
+
+func (tr *GridTransformer) fromNationalGrid(c *OSGB36Coordinate, diag *Diagnostics) (ETRS89Coordinate, error) {
 	etrs89Coord, etrs89Height, err := tr.fromOSGB36(&planeCoord{
 		easting:  c.Easting,
 		northing: c.Northing,
-	}, c.Height)
+	}, c.Height, diag)
+	if tr.fallsBack(err, c.Easting, c.Northing) {
+		return helmertFromNationalGrid(c)
+	}
 	if err != nil {
-		return nil, err
+		return ETRS89Coordinate{}, err
+	}
+	if err := tr.checkVerticalDatum(&etrs89Coord, c.VerticalDatum); err != nil {
+		return ETRS89Coordinate{}, err
 	}
 
-	etrs89Lat, etrs89Lon := nationalGridProjection.fromPlaneCoord(etrs89Coord, grs80Ellipsoid)
+	return etrs89FromPlaneCoord(&etrs89Coord, etrs89Height)
+}
+
+// checkVerticalDatum returns ErrPointOutsideDatum if a vertical datum is
+// given and the ETRS89 grid position is outside its geoid region.
+func (tr *GridTransformer) checkVerticalDatum(etrs89Coord *planeCoord, datum VerticalDatum) error {
+	if datum == (VerticalDatum{}) {
+		return nil
+	}
+	region, err := tr.region(etrs89Coord)
+	if err != nil {
+		return err
+	}
+	if regionVerticalDatums[region] != datum {
+		return ErrPointOutsideDatum
+	}
+	return nil
+}
+
+func etrs89ToPlaneCoord(c *ETRS89Coordinate) planeCoord {
+	return nationalGridGRS80.toPlaneCoord(degreesToRadians(c.Lat), degreesToRadians(c.Lon))
+}
+
+func etrs89FromPlaneCoord(etrs89Coord *planeCoord, etrs89Height float64) (ETRS89Coordinate, error) {
+	etrs89Lat, etrs89Lon, err := nationalGridGRS80.fromPlaneCoord(etrs89Coord)
+	if err != nil {
+		return ETRS89Coordinate{}, err
+	}
 	degreeLat := radiansToDegrees(etrs89Lat)
 	degreeLon := radiansToDegrees(etrs89Lon)
 
-	return &ETRS89Coordinate{
+	return ETRS89Coordinate{
 		Lat:    degreeLat,
 		Lon:    degreeLon,
 		Height: etrs89Height,
 	}, nil
 }
 
-func nearestGeoidRegion(etrs89Coord *planeCoord, rs *shiftRecords) geoidRegion {
+func nearestGeoidRegion(etrs89Coord *planeCoord, rs *shiftRecords) GeoidRegion {
 
 	dx := etrs89Coord.easting - float64(rs.s0.etrs89Easting)
 	t := dx / 1000.0
@@ -107,87 +262,72 @@ func nearestGeoidRegion(etrs89Coord *planeCoord, rs *shiftRecords) geoidRegion {
 	return rs.s3.geoidRegion
 }
 
-func (tr *transformer) toOSGB36(etrs89Coord *planeCoord, etrs89Height float64) (*planeCoord, float64, geoidRegion, error) {
+func (tr *GridTransformer) toOSGB36(etrs89Coord *planeCoord, etrs89Height float64) (planeCoord, float64, GeoidRegion, error) {
+	if err := tr.acquireGrid(); err != nil {
+		return planeCoord{}, 0, Region_FOULA, err
+	}
+	defer tr.releaseGrid()
 
-	rs, err := tr.getShiftRecords(etrs89Coord)
+	rs, shiftEast, shiftNorth, geoidHeight, err := tr.gridShifts(etrs89Coord)
 	if err != nil {
-		return nil, 0, Region_FOULA, err
+		return planeCoord{}, 0, Region_FOULA, err
 	}
 
-	dx := etrs89Coord.easting - float64(rs.s0.etrs89Easting)
-	t := dx / 1000.0
-	it := 1 - t
-	dy := etrs89Coord.northing - float64(rs.s0.etrs89Northing)
-	u := dy / 1000.0
-	iu := 1 - u
-	shiftEast := it*iu*rs.s0.ostnEastShift +
-		t*iu*rs.s1.ostnEastShift +
-		t*u*rs.s2.ostnEastShift +
-		it*u*rs.s3.ostnEastShift
-
-	shiftNorth := it*iu*rs.s0.ostnNorthShift +
-		t*iu*rs.s1.ostnNorthShift +
-		t*u*rs.s2.ostnNorthShift +
-		it*u*rs.s3.ostnNorthShift
+	geoidRegion := nearestGeoidRegion(etrs89Coord, &rs)
 
-	geoidHeight := it*iu*rs.s0.ostnGeoidHeight +
-		t*iu*rs.s1.ostnGeoidHeight +
-		t*u*rs.s2.ostnGeoidHeight +
-		it*u*rs.s3.ostnGeoidHeight
-
-	geoidRegion := nearestGeoidRegion(etrs89Coord, rs)
-
-	return &planeCoord{
+	return planeCoord{
 		easting:  etrs89Coord.easting + shiftEast,
 		northing: etrs89Coord.northing + shiftNorth,
 	}, etrs89Height - geoidHeight, geoidRegion, nil
 }
 
-func (tr *transformer) fromOSGB36(osgb36Coord *planeCoord, odnHeight float64) (*planeCoord, float64, error) {
-
-	etrs89Coord := &planeCoord{
-		easting:  osgb36Coord.easting,
-		northing: osgb36Coord.northing,
+func (tr *GridTransformer) fromOSGB36(osgb36Coord *planeCoord, odnHeight float64, diag *Diagnostics) (planeCoord, float64, error) {
+	if err := tr.acquireGrid(); err != nil {
+		return planeCoord{}, 0, err
+	}
+	defer tr.releaseGrid()
+	if tr.useInverseGrid {
+		if etrs89Coord, etrs89Height, ok := tr.inverseEstimate(osgb36Coord, odnHeight); ok {
+			return tr.iterateFromOSGB36(osgb36Coord, odnHeight, etrs89Coord, etrs89Height, diag)
+		}
 	}
-	etrs89Height := odnHeight
+	return tr.iterateFromOSGB36(osgb36Coord, odnHeight, *osgb36Coord, odnHeight, diag)
+}
+
+// iterateFromOSGB36 iteratively refines an initial ETRS89 estimate of an OSGB36 position.
+func (tr *GridTransformer) iterateFromOSGB36(osgb36Coord *planeCoord, odnHeight float64, etrs89Coord planeCoord, etrs89Height float64, diag *Diagnostics) (planeCoord, float64, error) {
 
 	// Iteatively find the map coordinate shift.
-	for {
+	for iteration := 1; ; iteration++ {
+		if iteration > tr.maxIterations {
+			return planeCoord{}, 0, ErrNoConvergence
+		}
 
-		rs, err := tr.getShiftRecords(etrs89Coord)
+		_, shiftEast, shiftNorth, geoidHeight, err := tr.gridShifts(&etrs89Coord)
 		if err != nil {
-			return nil, 0, err
+			return planeCoord{}, 0, err
 		}
 
-		dx := etrs89Coord.easting - float64(rs.s0.etrs89Easting)
-		t := dx / 1000.0
-		it := 1 - t
-		dy := etrs89Coord.northing - float64(rs.s0.etrs89Northing)
-		u := dy / 1000.0
-		iu := 1 - u
-		shiftEast := it*iu*rs.s0.ostnEastShift +
-			t*iu*rs.s1.ostnEastShift +
-			t*u*rs.s2.ostnEastShift +
-			it*u*rs.s3.ostnEastShift
-
-		shiftNorth := it*iu*rs.s0.ostnNorthShift +
-			t*iu*rs.s1.ostnNorthShift +
-			t*u*rs.s2.ostnNorthShift +
-			it*u*rs.s3.ostnNorthShift
-
-		geoidHeight := it*iu*rs.s0.ostnGeoidHeight +
-			t*iu*rs.s1.ostnGeoidHeight +
-			t*u*rs.s2.ostnGeoidHeight +
-			it*u*rs.s3.ostnGeoidHeight
-
-		const epsilon = 0.0001
-
 		newEasting := osgb36Coord.easting - shiftEast
 		newNorthing := osgb36Coord.northing - shiftNorth
 		newHeight := odnHeight + geoidHeight
-		if math.Abs(etrs89Coord.easting-newEasting) <= epsilon &&
-			math.Abs(etrs89Coord.northing-newNorthing) <= epsilon &&
-			math.Abs(etrs89Height-newHeight) <= epsilon {
+		dEasting := math.Abs(etrs89Coord.easting - newEasting)
+		dNorthing := math.Abs(etrs89Coord.northing - newNorthing)
+		dHeight := math.Abs(etrs89Height - newHeight)
+		if diag != nil {
+			diag.Iterations = iteration
+			diag.Steps = append(diag.Steps, IterationStep{
+				Easting:  newEasting,
+				Northing: newNorthing,
+				Height:   newHeight,
+			})
+			diag.EastingResidual = dEasting
+			diag.NorthingResidual = dNorthing
+			diag.HeightResidual = dHeight
+		}
+		if dEasting <= tr.tolerance &&
+			dNorthing <= tr.tolerance &&
+			dHeight <= tr.tolerance {
 			break
 		}
 		etrs89Coord.easting = newEasting
@@ -199,23 +339,46 @@ func (tr *transformer) fromOSGB36(osgb36Coord *planeCoord, odnHeight float64) (*
 }
 
 // NewOSTN02Transformer returns a transformer that uses OSTN02/OSGM02
-func NewOSTN02Transformer() (CoordinateTransformer, error) {
-	records, err := readRecords(translationVectorFile02)
-	if err != nil {
-		return nil, err
-	}
-	return &transformer{
-		records: records,
-	}, nil
+func NewOSTN02Transformer(opts ...Option) (*GridTransformer, error) {
+	return newTransformer(translationVectorFile02, ostn02Model, opts)
 }
 
 // NewOSTN15Transformer returns a transformer that uses OSTN15/OSGM15
-func NewOSTN15Transformer() (CoordinateTransformer, error) {
-	records, err := readRecords(translationVectorFile15)
+func NewOSTN15Transformer(opts ...Option) (*GridTransformer, error) {
+	return newTransformer(translationVectorFile15, ostn15Model, opts)
+}
+
+func newTransformer(translationVectorFile string, model *gridModel, opts []Option) (*GridTransformer, error) {
+	tr, err := configureTransformer(opts)
 	if err != nil {
 		return nil, err
 	}
-	return &transformer{
-		records: records,
-	}, nil
+	if tr.records == nil {
+		records, err := readRecords(translationVectorFile)
+		if err != nil {
+			return nil, err
+		}
+		tr.records = records
+		tr.model = model
+		tr.asset = translationVectorFile
+	}
+	if err := tr.checkExpectedChecksum(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// configureTransformer returns a transformer with the default settings and
+// the options applied, without a grid unless WithGrid is given.
+func configureTransformer(opts []Option) (*GridTransformer, error) {
+	tr := &GridTransformer{
+		tolerance:     DefaultTolerance,
+		maxIterations: DefaultMaxIterations,
+	}
+	for _, opt := range opts {
+		if err := opt(tr); err != nil {
+			return nil, err
+		}
+	}
+	return tr, nil
 }
@@ -0,0 +1,162 @@
+package osgb
+
+// Pipeline is a reusable sequence of steps transforming coordinates between
+// two coordinate reference systems.
+type Pipeline struct {
+	steps []step
+}
+
+// pipelineCoord is a coordinate passed between pipeline steps. Geographic
+// coordinates are held as longitude and latitude in radians, as in PROJ.
+type pipelineCoord struct {
+	x, y, z float64
+}
+
+type step interface {
+	forward(c pipelineCoord) (pipelineCoord, error)
+	inverse(c pipelineCoord) (pipelineCoord, error)
+}
+
+// Forward transforms a coordinate through each step of the pipeline in turn.
+func (p *Pipeline) Forward(x, y, z float64) (float64, float64, float64, error) {
+	c := pipelineCoord{x: x, y: y, z: z}
+	for _, s := range p.steps {
+		var err error
+		if c, err = s.forward(c); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return c.x, c.y, c.z, nil
+}
+
+// Inverse transforms a coordinate back through the pipeline, inverting each step
+// in reverse order.
+func (p *Pipeline) Inverse(x, y, z float64) (float64, float64, float64, error) {
+	c := pipelineCoord{x: x, y: y, z: z}
+	for i := len(p.steps) - 1; i >= 0; i-- {
+		var err error
+		if c, err = p.steps[i].inverse(c); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return c.x, c.y, c.z, nil
+}
+
+// invertedStep swaps the directions of a step.
+type invertedStep struct {
+	step
+}
+
+func (s invertedStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	return s.step.inverse(c)
+}
+
+func (s invertedStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	return s.step.forward(c)
+}
+
+// degreesStep converts longitude and latitude from degrees to radians.
+type degreesStep struct{}
+
+func (degreesStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	return pipelineCoord{x: degreesToRadians(c.x), y: degreesToRadians(c.y), z: c.z}, nil
+}
+
+func (degreesStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	return pipelineCoord{x: radiansToDegrees(c.x), y: radiansToDegrees(c.y), z: c.z}, nil
+}
+
+// cartesianStep converts geographic coordinates on an ellipsoid to Cartesian coordinates.
+type cartesianStep struct {
+	el *ellipsoid
+}
+
+func (s cartesianStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	cartCoord := s.el.geographicToCartesian(&geographicCoord{lat: c.y, lon: c.x, height: c.z})
+	return pipelineCoord{x: cartCoord.x, y: cartCoord.y, z: cartCoord.z}, nil
+}
+
+func (s cartesianStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	geoCoord, err := s.el.cartesianToGeographic(&cartesianCoord{x: c.x, y: c.y, z: c.z})
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: geoCoord.lon, y: geoCoord.lat, z: geoCoord.height}, nil
+}
+
+// helmertStep applies a Helmert transformation to Cartesian coordinates.
+type helmertStep struct {
+	h *helmert
+}
+
+func (s helmertStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	cartCoord := s.h.apply(&cartesianCoord{x: c.x, y: c.y, z: c.z})
+	return pipelineCoord{x: cartCoord.x, y: cartCoord.y, z: cartCoord.z}, nil
+}
+
+func (s helmertStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	cartCoord := s.h.applyInverse(&cartesianCoord{x: c.x, y: c.y, z: c.z})
+	return pipelineCoord{x: cartCoord.x, y: cartCoord.y, z: cartCoord.z}, nil
+}
+
+// projectionStep projects geographic coordinates on an ellipsoid to eastings and northings.
+type projectionStep struct {
+	proj *Projection
+}
+
+func (s projectionStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	coord := s.proj.toPlaneCoord(c.y, c.x)
+	return pipelineCoord{x: coord.easting, y: coord.northing, z: c.z}, nil
+}
+
+func (s projectionStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	φ, λ, err := s.proj.fromPlaneCoord(&planeCoord{easting: c.x, northing: c.y})
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: λ, y: φ, z: c.z}, nil
+}
+
+// gridStep transforms ETRS89 geographic coordinates and ellipsoidal heights to
+// National Grid eastings, northings and orthometric heights with a grid transformer.
+type gridStep struct {
+	tr CoordinateTransformer
+}
+
+func (s gridStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	coord, err := s.tr.ToNationalGrid(NewETRS89Coord(radiansToDegrees(c.x), radiansToDegrees(c.y), c.z))
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: coord.Easting, y: coord.Northing, z: coord.Height}, nil
+}
+
+func (s gridStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	coord, err := s.tr.FromNationalGrid(NewOSGB36Coord(c.x, c.y, c.z))
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: degreesToRadians(coord.Lon), y: degreesToRadians(coord.Lat), z: coord.Height}, nil
+}
+
+// geoidStep converts ETRS89 ellipsoidal heights to orthometric heights, leaving
+// the geographic position unchanged.
+type geoidStep struct {
+	tr *GridTransformer
+}
+
+func (s geoidStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	ht, err := s.tr.ToOrthometricHeight(radiansToDegrees(c.x), radiansToDegrees(c.y), c.z)
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: c.x, y: c.y, z: ht.Height}, nil
+}
+
+func (s geoidStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	ht, err := s.tr.ToEllipsoidalHeight(radiansToDegrees(c.x), radiansToDegrees(c.y), c.z)
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: c.x, y: c.y, z: ht.Height}, nil
+}
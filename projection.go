@@ -1,91 +1,167 @@
 package osgb
 
 import (
+	"errors"
+	"fmt"
 	"math"
 )
 
-type projection struct {
+const (
+	// Latitude iteration converges when the meridional arc is within 0.01mm.
+	projectionTolerance     = 0.00001
+	maxProjectionIterations = 100
+)
+
+// ErrInvalidProjection indicates the parameters of a projection are out of range.
+var ErrInvalidProjection = errors.New("invalid projection")
+
+// Projection is a transverse Mercator projection of an ellipsoid, as used by the
+// National Grid and UTM. The terms that depend only on the ellipsoid and the
+// projection parameters are computed once, when the projection is created.
+type Projection struct {
 	scaleFactor        float64
 	geodeticTrueOrigin geographicCoord
 	mapTrueOrigin      planeCoord
+
+	// e^2 - ellipsoid squared eccentricity constant.
+	e2 float64
+	// aF0, bF0 - semi-major and semi-minor axes scaled by F0 (metres)
+	aF0, bF0 float64
+	// Coefficients of Ma, Mb, Mc and Md in the meridional arc (B6)
+	ma, mb, mc, md float64
 }
 
 var (
-	nationalGridProjection = &projection{
-		scaleFactor: 0.9996012717,
+	// nationalGridAiry projects OSGB36 positions to the National Grid.
+	nationalGridAiry = newProjection(airyEllipsoid, degreesToRadians(49.0), degreesToRadians(-2.0), 0.9996012717, 400000, -100000)
+	// nationalGridGRS80 projects ETRS89 positions to the National Grid, as the OSTN grid is defined.
+	nationalGridGRS80 = newProjection(grs80Ellipsoid, degreesToRadians(49.0), degreesToRadians(-2.0), 0.9996012717, 400000, -100000)
+)
+
+// NewTransverseMercator returns a transverse Mercator projection of an ellipsoid, with its
+// true origin at a latitude and longitude in decimal degrees, a scale factor on the central
+// meridian, and the easting and northing of the true origin in metres.
+func NewTransverseMercator(el Ellipsoid, lat0, lon0, scaleFactor, falseEasting, falseNorthing float64) (*Projection, error) {
+	if !(el.SemiMinorAxis > 0 && el.SemiMinorAxis <= el.SemiMajorAxis) || math.IsInf(el.SemiMajorAxis, 0) {
+		return nil, fmt.Errorf("%w: ellipsoid axes %g and %g", ErrInvalidProjection, el.SemiMajorAxis, el.SemiMinorAxis)
+	}
+	if !(lat0 >= -90 && lat0 <= 90) || !(lon0 >= -180 && lon0 <= 180) {
+		return nil, fmt.Errorf("%w: true origin (%g, %g)", ErrInvalidProjection, lon0, lat0)
+	}
+	if !(scaleFactor > 0) || math.IsInf(scaleFactor, 1) {
+		return nil, fmt.Errorf("%w: scale factor %g", ErrInvalidProjection, scaleFactor)
+	}
+	if math.IsNaN(falseEasting) || math.IsInf(falseEasting, 0) || math.IsNaN(falseNorthing) || math.IsInf(falseNorthing, 0) {
+		return nil, fmt.Errorf("%w: false origin (%g, %g)", ErrInvalidProjection, falseEasting, falseNorthing)
+	}
+	return newProjection(el.ellipsoid(), degreesToRadians(lat0), degreesToRadians(lon0), scaleFactor, falseEasting, falseNorthing), nil
+}
+
+func newProjection(el *ellipsoid, φ0, λ0, f0, e0, n0 float64) *Projection {
+	// a - semi-major axis (metres)
+	a := el.semiMajorAxis
+	// b - semi-minor axis (metres)
+	b := el.semiMinorAxis
+	// (B2) n = a−b/a+b
+	n := (a - b) / (a + b)
+	// n^2
+	n2 := n * n
+	// n^3
+	n3 := n2 * n
+	return &Projection{
+		scaleFactor: f0,
 		geodeticTrueOrigin: geographicCoord{
-			lat: degreesToRadians(49.0),
-			lon: degreesToRadians(-2.0),
+			lat: φ0,
+			lon: λ0,
 		},
 		mapTrueOrigin: planeCoord{
-			easting:  400000,
-			northing: -100000,
+			easting:  e0,
+			northing: n0,
 		},
+		e2:  el.eccentricity(),
+		aF0: a * f0,
+		bF0: b * f0,
+		// (1+n+(5/4)n^2 +(5/4)n^3)
+		ma: 1 + n + 5.0*n2/4.0 + 5.0*n3/4.0,
+		// (3n+3n^2 + (21/8)n^3)
+		mb: 3*n + 3*n2 + (21.0/8.0)*n3,
+		// ((15/8)n^2 + (15/8)n^3)
+		mc: (15.0/8.0)*n2 + (15.0/8.0)*n3,
+		// (35/24)n^3
+		md: (35.0 / 24.0) * n3,
 	}
-)
+}
 
-func (proj *projection) toPlaneCoord(φ, λ float64, el *ellipsoid) *planeCoord {
-	// a - semi0major axis (metres)
-	a := el.semiMajorAxis
-	// b - semi-minor axis (metres)
-	b := el.semiMinorAxis
+// Forward projects a longitude and latitude in decimal degrees to an easting and northing in metres.
+func (proj *Projection) Forward(lon, lat float64) (float64, float64) {
+	coord := proj.toPlaneCoord(degreesToRadians(lat), degreesToRadians(lon))
+	return coord.easting, coord.northing
+}
+
+// Inverse returns the longitude and latitude in decimal degrees of an easting and northing in metres.
+func (proj *Projection) Inverse(easting, northing float64) (float64, float64, error) {
+	φ, λ, err := proj.fromPlaneCoord(&planeCoord{easting: easting, northing: northing})
+	if err != nil {
+		return 0, 0, err
+	}
+	return radiansToDegrees(λ), radiansToDegrees(φ), nil
+}
+
+// meridionalArc returns the developed meridional arc M from the true origin to a latitude.
+func (proj *Projection) meridionalArc(φ float64) float64 {
+	// φ - φ0
+	dφ0 := φ - proj.geodeticTrueOrigin.lat
+	// φ + φ0
+	aφ0 := φ + proj.geodeticTrueOrigin.lat
+
+	//Ma = (1+n+(5/4)n^2 +(5/4)n^3)(φ−φ0)
+	ma := proj.ma * dφ0
+	//Mb = (3n+3n^2 + (21/8)n^3)sin(φ−φ0)cos(φ+φ0)
+	mb := proj.mb * math.Sin(dφ0) * math.Cos(aφ0)
+	//Mc = ((15/8)n^2 + (15/8)n^3)sin(2(φ−φ0))cos(2(φ+φ0))
+	mc := proj.mc * math.Sin(2*dφ0) * math.Cos(2*aφ0)
+	//Md = (35/24)n^3 sin(3(φ −φ0))cos(3(φ +φ0))
+	md := proj.md * math.Sin(3*dφ0) * math.Cos(3*aφ0)
+
+	// (B6) M = bF0 (Ma - Mb + Mc - Md)
+	return proj.bF0 * (ma + mc - (mb + md))
+}
+
+func (proj *Projection) toPlaneCoord(φ, λ float64) planeCoord {
 	// e^2 - ellipsoid squared eccentricity constant.
-	e2 := el.eccentricity()
+	e2 := proj.e2
 	// N0 – northing of true origin;
 	n0 := proj.mapTrueOrigin.northing
 	// E0 – easting of true origin;
 	e0 := proj.mapTrueOrigin.easting
-	// F0 – scale factor on central meridian;
-	f0 := proj.scaleFactor
-	// φ0 – latitude of true origin; and
-	φ0 := proj.geodeticTrueOrigin.lat
 	// λ0 – longitude of true origin and central meridian.
 	λ0 := proj.geodeticTrueOrigin.lon
 
-	// (B2) n = a−b/a+b
-	n := (a - b) / (a + b)
-
+	// sinφ, cosφ
+	sφ, cφ := math.Sincos(φ)
+	// sin^2(φ)
+	s2φ := sφ * sφ
 	//tan^2(φ)
-	t2φ := math.Tan(φ) * math.Tan(φ)
+	t2φ := s2φ / (cφ * cφ)
 	//tan^4(φ)
 	t4φ := t2φ * t2φ
-	// sinφ
-	sφ := math.Sin(φ)
-	// sin^2(φ)
-	s2φ := sφ * sφ
-	// cosφ
-	cφ := math.Cos(φ)
 	// cos^3φ
 	c3φ := cφ * cφ * cφ
 	// cos^5φ
 	c5φ := c3φ * cφ * cφ
-	// n^2
-	n2 := n * n
-	// n^3
-	n3 := n2 * n
 
+	// 1 − e^2 sin^2(φ)
+	w := 1 - e2*s2φ
+	sw := math.Sqrt(w)
 	// (B3) ν = aF0 (1 − e^2 sin^2(φ)) ^ −0.5
-	ν := a * f0 * math.Pow(1-e2*s2φ, -0.5)
+	ν := proj.aF0 / sw
 	// (B4) ρ=aF0(1−e^2)(1−e^2 sin^2(φ)) ^−1.5
-	ρ := a * f0 * (1 - e2) * math.Pow(1-e2*s2φ, -1.5)
+	ρ := proj.aF0 * (1 - e2) / (w * sw)
 	// (B5) η2 = ν/ρ − 1
 	η2 := ν/ρ - 1
-	// φ - φ0
-	dφ0 := φ - φ0
-	// φ + φ0
-	aφ0 := φ + φ0
 
-	//Ma = (1+n+(5/4)n^2 +(5/4)n^3)(φ−φ0)
-	ma := (1 + n + 5.0*n2/4.0 + 5.0*n3/4.0) * dφ0
-	//Mb = (3n+3n^2 + (21/8)n^3)sin(φ−φ0)cos(φ+φ0)
-	mb := (3*n + 3*n2 + (21.0/8.0)*n3) * math.Sin(dφ0) * math.Cos(aφ0)
-	//Mc = ((15/8)n^2 + (15/8)n^3)sin(2(φ−φ0))cos(2(φ+φ0))
-	mc := ((15.0/8.0)*n2 + (15.0/8.0)*n3) * math.Sin(2*dφ0) * math.Cos(2*(aφ0))
-	//Md = (35/24)n^3 sin(3(φ −φ0))cos(3(φ +φ0))
-	md := (35.0 / 24.0) * n3 * math.Sin(3*dφ0) * math.Cos(3*(aφ0))
-
-	// (B6) M = bF0 (Ma - Mb + Mc - Md)
-	m := b * f0 * (ma + mc - (mb + md))
+	// (B6) M
+	m := proj.meridionalArc(φ)
 
 	//I = M + N0
 	si := m + n0
@@ -112,96 +188,65 @@ func (proj *projection) toPlaneCoord(φ, λ float64, el *ellipsoid) *planeCoord
 	dλ0 := λ - λ0
 	// (λ - λ0)^2
 	d2λ0 := dλ0 * dλ0
-	// (λ - λ0)^3
-	d3λ0 := d2λ0 * dλ0
-	// (λ - λ0)^4
-	d4λ0 := d2λ0 * d2λ0
-	// (λ - λ0)^5
-	d5λ0 := d3λ0 * d2λ0
-	// (λ - λ0)^6
-	d6λ0 := d3λ0 * d3λ0
 
 	// (B7) N =I+II(λ−λ0)^2 +III(λ−λ0)^4 +IIIA(λ−λ0)^6
-	northing := si + sii*d2λ0 + siii*d4λ0 + siiia*d6λ0
+	northing := si + d2λ0*(sii+d2λ0*(siii+d2λ0*siiia))
 
 	// (B8) E = E0 +IV(λ−λ0)+V(λ−λ0)^3 +VI(λ−λ0)^5
-	easting := e0 + siv*dλ0 + sv*d3λ0 + svi*d5λ0
+	easting := e0 + dλ0*(siv+d2λ0*(sv+d2λ0*svi))
 
-	return &planeCoord{
+	return planeCoord{
 		easting:  easting,
 		northing: northing,
 	}
 }
 
-func (proj *projection) fromPlaneCoord(coord *planeCoord, el *ellipsoid) (float64, float64) {
-	// a - semi0major axis (metres)
-	a := el.semiMajorAxis
-	// b - semi-minor axis (metres)
-	b := el.semiMinorAxis
+func (proj *Projection) fromPlaneCoord(coord *planeCoord) (float64, float64, error) {
 	// e^2 - ellipsoid squared eccentricity constant.
-	e2 := el.eccentricity()
+	e2 := proj.e2
 	// N0 – northing of true origin;
 	n0 := proj.mapTrueOrigin.northing
 	// E0 – easting of true origin;
 	e0 := proj.mapTrueOrigin.easting
-	// F0 – scale factor on central meridian;
-	f0 := proj.scaleFactor
 	// φ0 – latitude of true origin; and
 	φ0 := proj.geodeticTrueOrigin.lat
 	// λ0 – longitude of true origin and central meridian.
 	λ0 := proj.geodeticTrueOrigin.lon
-	// (B2) n = a−b/a+b
-	n := (a - b) / (a + b)
 
 	φ := φ0
 	m := 0.0
 
-	for {
+	for iteration := 1; ; iteration++ {
+		if iteration > maxProjectionIterations {
+			return 0, 0, ErrNoConvergence
+		}
 
 		// (C2) φnew = (N-N0-M)/(aF0) +φ′
-		φ = φ + (coord.northing-(n0+m))/(a*f0)
-
-		// n^2
-		n2 := n * n
-		// n^3
-		n3 := n2 * n
-
-		// φ - φ0
-		dφ0 := φ - φ0
-		// φ + φ0
-		aφ0 := φ + φ0
-
-		//Ma = (1+n+(5/4)n^2 +(5/4)n^3)(φ−φ0)
-		ma := (1 + n + 5.0*n2/4.0 + 5.0*n3/4.0) * dφ0
-		//Mb = (3n+3n^2 + (21/8)n^3)sin(φ−φ0)cos(φ+φ0)
-		mb := (3*n + 3*n2 + (21.0/8.0)*n3) * math.Sin(dφ0) * math.Cos(aφ0)
-		//Mc = ((15/8)n^2 + (15/8)n^3)sin(2(φ−φ0))cos(2(φ+φ0))
-		mc := ((15.0/8.0)*n2 + (15.0/8.0)*n3) * math.Sin(2*dφ0) * math.Cos(2*(aφ0))
-		//Md = (35/24)n^3 sin(3(φ −φ0))cos(3(φ +φ0))
-		md := (35.0 / 24.0) * n3 * math.Sin(3*dφ0) * math.Cos(3*(aφ0))
-
-		// (B6) M = bF0 (Ma - Mb + Mc - Md)
-		m = b * f0 * (ma + mc - (mb + md))
-		if coord.northing-(n0+m) < 0.00001 {
+		φ = φ + (coord.northing-(n0+m))/proj.aF0
+
+		// (B6) M
+		m = proj.meridionalArc(φ)
+		if math.Abs(coord.northing-(n0+m)) < projectionTolerance {
 			break
 		}
 	}
 
-	// sinφ
-	sφ := math.Sin(φ)
-	// cosφ
-	cφ := math.Cos(φ)
+	// sinφ, cosφ
+	sφ, cφ := math.Sincos(φ)
 	// sin^2(φ)
 	s2φ := sφ * sφ
+	// 1 − e^2 sin^2(φ)
+	w := 1 - e2*s2φ
+	sw := math.Sqrt(w)
 	// (B3) ν = aF0 (1 − e^2 sin^2(φ)) ^ −0.5
-	ν := a * f0 * math.Pow(1-e2*s2φ, -0.5)
+	ν := proj.aF0 / sw
 	// (B4) ρ=aF0(1−e^2)(1−e^2 sin^2(φ)) ^−1.5
-	ρ := a * f0 * (1 - e2) * math.Pow(1-e2*s2φ, -1.5)
+	ρ := proj.aF0 * (1 - e2) / (w * sw)
 	// (B5) η2 = ν/ρ − 1
 	η2 := ν/ρ - 1
 
 	// tanφ
-	tφ := math.Tan(φ)
+	tφ := sφ / cφ
 	// tan^2φ
 	t2φ := tφ * tφ
 	// tan^4φ
@@ -249,5 +294,5 @@ func (proj *projection) fromPlaneCoord(coord *planeCoord, el *ellipsoid) (float6
 	// (C4) λ=λ0 +X(E−E0)−XI(E−E0)^3 +XII(E−E0)^5 −XIIA(E−E0)^7
 	λ := λ0 + sx*de - sxi*d3e + sxii*d5e - sxiia*d7e
 
-	return φ, λ
+	return φ, λ, nil
 }
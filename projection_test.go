@@ -1,6 +1,10 @@
 package osgb
 
-import "testing"
+import (
+	"errors"
+	"math"
+	"testing"
+)
 
 func TestLatLonToEastNort(t *testing.T) {
 	lat, err := dmsToDecimal(52, 39, 27.2531, north)
@@ -16,7 +20,7 @@ func TestLatLonToEastNort(t *testing.T) {
 	expectedEast := 651409.903
 	expectedNorth := 313177.270
 
-	coord := nationalGridProjection.toPlaneCoord(latRadians, lonRadians, airyEllipsoid)
+	coord := nationalGridAiry.toPlaneCoord(latRadians, lonRadians)
 
 	checkDistance(t, "east", expectedEast, coord.easting)
 	checkDistance(t, "north", expectedNorth, coord.northing)
@@ -41,8 +45,96 @@ func TestEastNortToLatLon(t *testing.T) {
 		northing: northing,
 	}
 
-	lat, lon := nationalGridProjection.fromPlaneCoord(coord, airyEllipsoid)
+	lat, lon, err := nationalGridAiry.fromPlaneCoord(coord)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	checkAngle(t, "latitude", expectedLatRadians, lat)
 	checkAngle(t, "longitude", expectedLonRadians, lon)
 }
+
+func TestEastNortToLatLonSouthOfTrueOrigin(t *testing.T) {
+	// South of the true origin the first latitude estimate overshoots, so the
+	// meridional arc residual is negative until the iteration converges.
+	for _, coord := range []*planeCoord{
+		{easting: 400000, northing: -150000},
+		{easting: 300000, northing: -400000},
+	} {
+		lat, lon, err := nationalGridAiry.fromPlaneCoord(coord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip := nationalGridAiry.toPlaneCoord(lat, lon)
+		checkDistance(t, "east", coord.easting, roundTrip.easting)
+		checkDistance(t, "north", coord.northing, roundTrip.northing)
+	}
+}
+
+func TestTransverseMercator(t *testing.T) {
+	proj, err := NewTransverseMercator(EllipsoidAiry, 49, -2, 0.9996012717, 400000, -100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lat, err := dmsToDecimal(52, 39, 27.2531, north)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lon, err := dmsToDecimal(1, 43, 4.5177, east)
+	if err != nil {
+		t.Fatal(err)
+	}
+	easting, northing := proj.Forward(lon, lat)
+	checkDistance(t, "east", 651409.903, easting)
+	checkDistance(t, "north", 313177.270, northing)
+
+	actualLon, actualLat, err := proj.Inverse(easting, northing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAngle(t, "longitude", lon, actualLon)
+	checkAngle(t, "latitude", lat, actualLat)
+
+	// UTM zone 30N at its central meridian
+	utm, err := NewTransverseMercator(EllipsoidWGS84, 0, -3, 0.9996, 500000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	easting, _ = utm.Forward(-3, 52)
+	checkDistance(t, "UTM east", 500000, easting)
+
+	for _, p := range []struct {
+		el                                     Ellipsoid
+		lat0, lon0, k, eastOrigin, northOrigin float64
+	}{
+		{Ellipsoid{}, 49, -2, 1, 0, 0},
+		{Ellipsoid{SemiMajorAxis: 6356256.909, SemiMinorAxis: 6377563.396}, 49, -2, 1, 0, 0},
+		{EllipsoidAiry, 91, -2, 1, 0, 0},
+		{EllipsoidAiry, 49, -2, 0, 0, 0},
+		{EllipsoidAiry, 49, -2, 1, math.NaN(), 0},
+	} {
+		if _, err := NewTransverseMercator(p.el, p.lat0, p.lon0, p.k, p.eastOrigin, p.northOrigin); !errors.Is(err, ErrInvalidProjection) {
+			t.Errorf("%+v: expected %v, actual %v", p, ErrInvalidProjection, err)
+		}
+	}
+}
+
+func BenchmarkToPlaneCoord(b *testing.B) {
+	φ, λ := degreesToRadians(52.65757), degreesToRadians(1.71792)
+	var coord planeCoord
+	for i := 0; i < b.N; i++ {
+		coord = nationalGridAiry.toPlaneCoord(φ, λ)
+	}
+	if coord.easting == 0 {
+		b.Fatal("expected a projected easting")
+	}
+}
+
+func BenchmarkFromPlaneCoord(b *testing.B) {
+	coord := planeCoord{easting: 651409.903, northing: 313177.270}
+	for i := 0; i < b.N; i++ {
+		if _, _, err := nationalGridAiry.fromPlaneCoord(&coord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
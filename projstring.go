@@ -0,0 +1,515 @@
+package osgb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// projEllipsoids maps PROJ ellipsoid names to the registry.
+var projEllipsoids = map[string]Ellipsoid{
+	"airy":     EllipsoidAiry,
+	"mod_airy": EllipsoidAiryModified,
+	"GRS80":    EllipsoidGRS80,
+	"WGS84":    EllipsoidWGS84,
+	"intl":     EllipsoidInternational1924,
+	"clrk66":   EllipsoidClarke1866,
+}
+
+// projHorizontalGrids and projVerticalGrids map the names of published OSTN/OSGM grid files to the embedded grids.
+// The horizontal grids shift OSGB36 to ETRS89; the geoid grids give the separation
+// between the ETRS89 ellipsoid and ODN.
+var (
+	projHorizontalGrids = map[string]*embeddedGrid{
+		"OSTN15_NTv2_OSGBtoETRS.gsb":       embeddedOSTN15,
+		"uk_os_OSTN15_NTv2_OSGBtoETRS.tif": embeddedOSTN15,
+		"OSTN02_NTv2.gsb":                  embeddedOSTN02,
+		"uk_os_OSTN02_NTv2_OSGBtoETRS.tif": embeddedOSTN02,
+	}
+	projVerticalGrids = map[string]*embeddedGrid{
+		"OSGM15_GB.gtx":       embeddedOSTN15,
+		"uk_os_OSGM15_GB.tif": embeddedOSTN15,
+		"OSGM02_GB.gtx":       embeddedOSTN02,
+	}
+)
+
+// projAngularUnits and projLinearUnits give unitconvert units in radians and metres.
+var (
+	projAngularUnits = map[string]float64{
+		"rad":  1,
+		"deg":  radianInDegrees,
+		"grad": math.Pi / 200,
+	}
+	projLinearUnits = map[string]float64{
+		"m":     1,
+		"km":    1000,
+		"ft":    0.3048,
+		"us-ft": 1200.0 / 3937,
+	}
+)
+
+// ParsePipeline builds a pipeline from a PROJ pipeline string, such as
+//
+//	+proj=pipeline +step +inv +proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717
+//	+x_0=400000 +y_0=-100000 +ellps=airy +step +proj=hgridshift
+//	+grids=uk_os_OSTN15_NTv2_OSGBtoETRS.tif +step +proj=unitconvert +xy_in=rad +xy_out=deg
+//
+// The supported operations are axisswap, unitconvert, cart, helmert, tmerc, hgridshift
+// and vgridshift, with +inv to invert a step, or the whole pipeline when given before
+// the first +step. Grid shifts use the embedded OSTN/OSGM
+// grids in place of the published files they are equivalent to, such as
+// OSTN15_NTv2_OSGBtoETRS.gsb and OSGM15_GB.gtx. As in PROJ, vgridshift subtracts the
+// geoid separation from heights in the forward direction unless +multiplier is given.
+func ParsePipeline(definition string) (*Pipeline, error) {
+	return parsePipeline(definition, func(name string, grids map[string]*embeddedGrid) (*GridTransformer, error) {
+		grid, ok := grids[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported grid %q", name)
+		}
+		return grid.get()
+	})
+}
+
+// gridResolver returns the grid transformer for a grid file name.
+type gridResolver func(name string, grids map[string]*embeddedGrid) (*GridTransformer, error)
+
+type projParams map[string]string
+
+func parsePipeline(definition string, resolve gridResolver) (*Pipeline, error) {
+	var global projParams
+	var steps []projParams
+	// inv is set when +inv is given on the pipeline itself, rather than a step
+	var inv bool
+	current := projParams{}
+	for _, token := range strings.Fields(definition) {
+		token = strings.TrimPrefix(token, "+")
+		if token == "step" {
+			if global == nil {
+				global = current
+			} else {
+				steps = append(steps, current)
+			}
+			current = projParams{}
+			continue
+		}
+		key, value := token, ""
+		if i := strings.Index(token, "="); i >= 0 {
+			key, value = token[:i], token[i+1:]
+		}
+		current[key] = value
+	}
+	steps = append(steps, current)
+	inherited := make([]map[string]bool, len(steps))
+	if global != nil {
+		if global["proj"] != "pipeline" {
+			return nil, fmt.Errorf("invalid PROJ pipeline: steps given without +proj=pipeline")
+		}
+		delete(global, "proj")
+		_, inv = global["inv"]
+		delete(global, "inv")
+		// Global parameters apply to every step that doesn't override them,
+		// and are ignored by steps that don't use them.
+		for i, params := range steps {
+			inherited[i] = map[string]bool{}
+			for key, value := range global {
+				if _, ok := params[key]; !ok {
+					params[key] = value
+					inherited[i][key] = true
+				}
+			}
+		}
+	}
+
+	p := &Pipeline{}
+	for i, params := range steps {
+		s, err := params.step(resolve, inherited[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROJ pipeline step %d: %s", i+1, err)
+		}
+		p.steps = append(p.steps, s)
+	}
+	if inv {
+		// An inverted pipeline runs the inverse of each step in reverse order.
+		steps := p.steps
+		p.steps = make([]step, len(steps))
+		for i, s := range steps {
+			p.steps[len(steps)-1-i] = invertedStep{s}
+		}
+	}
+	return p, nil
+}
+
+func (params projParams) step(resolve gridResolver, inherited map[string]bool) (step, error) {
+	_, inv := params["inv"]
+	delete(params, "inv")
+	// Parameters PROJ accepts on any step that have no effect here
+	delete(params, "no_defs")
+	delete(params, "type")
+
+	proj, ok := params["proj"]
+	if !ok {
+		return nil, fmt.Errorf("missing +proj")
+	}
+	delete(params, "proj")
+
+	var s step
+	var err error
+	switch proj {
+	case "axisswap":
+		s, err = params.axisswap()
+	case "unitconvert":
+		s, err = params.unitconvert()
+	case "cart":
+		s, err = params.cart()
+	case "helmert":
+		s, err = params.helmert()
+	case "tmerc":
+		s, err = params.tmerc()
+	case "hgridshift":
+		s, err = params.hgridshift(resolve)
+	case "vgridshift":
+		s, err = params.vgridshift(resolve)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", proj)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", proj, err)
+	}
+	for key := range params {
+		if !inherited[key] {
+			return nil, fmt.Errorf("%s: unsupported parameter +%s", proj, key)
+		}
+	}
+	if inv {
+		s = invertedStep{s}
+	}
+	return s, nil
+}
+
+// take removes a parameter, reporting whether it was present.
+func (params projParams) take(key string) (string, bool) {
+	value, ok := params[key]
+	delete(params, key)
+	return value, ok
+}
+
+// float removes a numeric parameter, returning the default if it is absent.
+func (params projParams) float(key string, def float64) (float64, error) {
+	value, ok := params.take(key)
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid +%s=%s", key, value)
+	}
+	return f, nil
+}
+
+func (params projParams) ellipsoid() (*ellipsoid, error) {
+	name, ok := params.take("ellps")
+	if !ok {
+		name = "GRS80"
+	}
+	e, ok := projEllipsoids[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ellipsoid %q", name)
+	}
+	return e.ellipsoid(), nil
+}
+
+func (params projParams) grid(resolve gridResolver, grids map[string]*embeddedGrid) (*GridTransformer, error) {
+	value, ok := params.take("grids")
+	if !ok {
+		return nil, fmt.Errorf("missing +grids")
+	}
+	// Use the first grid available, ignoring the optional marker.
+	var err error
+	for _, name := range strings.Split(value, ",") {
+		var tr *GridTransformer
+		if tr, err = resolve(strings.TrimPrefix(name, "@"), grids); err == nil {
+			return tr, nil
+		}
+	}
+	return nil, err
+}
+
+// axisswapStep reorders and negates axes. Each entry of order is the 1-based input
+// axis of an output axis, negative to negate it.
+type axisswapStep struct {
+	order [3]int
+}
+
+func (params projParams) axisswap() (step, error) {
+	value, ok := params.take("order")
+	if !ok {
+		return nil, fmt.Errorf("missing +order")
+	}
+	s := axisswapStep{order: [3]int{1, 2, 3}}
+	seen := map[int]bool{}
+	for i, axis := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(axis)
+		if err != nil || i >= 3 || n == 0 || n < -3 || n > 3 || seen[axisIndex(n)] {
+			return nil, fmt.Errorf("invalid +order=%s", value)
+		}
+		seen[axisIndex(n)] = true
+		s.order[i] = n
+	}
+	// Axes beyond those given are unchanged, so must not have been moved.
+	for i := len(seen); i < 3; i++ {
+		if seen[i+1] {
+			return nil, fmt.Errorf("invalid +order=%s", value)
+		}
+	}
+	return s, nil
+}
+
+// axisIndex returns the 1-based axis of an axisswap order entry.
+func axisIndex(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (s axisswapStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	in := [3]float64{c.x, c.y, c.z}
+	var out [3]float64
+	for i, n := range s.order {
+		out[i] = in[axisIndex(n)-1]
+		if n < 0 {
+			out[i] = -out[i]
+		}
+	}
+	return pipelineCoord{x: out[0], y: out[1], z: out[2]}, nil
+}
+
+func (s axisswapStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	in := [3]float64{c.x, c.y, c.z}
+	var out [3]float64
+	for i, n := range s.order {
+		out[axisIndex(n)-1] = in[i]
+		if n < 0 {
+			out[axisIndex(n)-1] = -in[i]
+		}
+	}
+	return pipelineCoord{x: out[0], y: out[1], z: out[2]}, nil
+}
+
+// unitconvertStep scales horizontal and vertical coordinates.
+type unitconvertStep struct {
+	xy, z float64
+}
+
+func (params projParams) unitconvert() (step, error) {
+	xy, err := params.unitFactor("xy_in", "xy_out")
+	if err != nil {
+		return nil, err
+	}
+	z, err := params.unitFactor("z_in", "z_out")
+	if err != nil {
+		return nil, err
+	}
+	return unitconvertStep{xy: xy, z: z}, nil
+}
+
+// unitFactor returns the factor converting between the units of two parameters,
+// which must both be angular or both linear.
+func (params projParams) unitFactor(inKey, outKey string) (float64, error) {
+	in, inOK := params.take(inKey)
+	out, outOK := params.take(outKey)
+	if !inOK && !outOK {
+		return 1, nil
+	}
+	if !inOK || !outOK {
+		return 0, fmt.Errorf("+%s and +%s must be given together", inKey, outKey)
+	}
+	for _, units := range []map[string]float64{projAngularUnits, projLinearUnits} {
+		inFactor, inFound := units[in]
+		outFactor, outFound := units[out]
+		if inFound && outFound {
+			return inFactor / outFactor, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot convert %s to %s", in, out)
+}
+
+func (s unitconvertStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	return pipelineCoord{x: c.x * s.xy, y: c.y * s.xy, z: c.z * s.z}, nil
+}
+
+func (s unitconvertStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	return pipelineCoord{x: c.x / s.xy, y: c.y / s.xy, z: c.z / s.z}, nil
+}
+
+func (params projParams) cart() (step, error) {
+	el, err := params.ellipsoid()
+	if err != nil {
+		return nil, err
+	}
+	return cartesianStep{el: el}, nil
+}
+
+func (params projParams) helmert() (step, error) {
+	var values [14]float64
+	keys := [14]string{"x", "y", "z", "s", "rx", "ry", "rz", "dx", "dy", "dz", "ds", "drx", "dry", "drz"}
+	rotated := false
+	for i, key := range keys {
+		_, present := params[key]
+		v, err := params.float(key, 0)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+		if present && strings.Contains(key, "r") {
+			rotated = true
+		}
+	}
+	epoch, err := params.float("t_epoch", 0)
+	if err != nil {
+		return nil, err
+	}
+	observed, err := params.float("t_obs", epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotations in the coordinate frame convention have the opposite sign.
+	sign := 1.0
+	convention, ok := params.take("convention")
+	switch {
+	case convention == "position_vector":
+	case convention == "coordinate_frame":
+		sign = -1
+	case !ok && !rotated:
+	default:
+		return nil, fmt.Errorf("+convention must be position_vector or coordinate_frame")
+	}
+
+	h := &helmert{
+		tx: values[0], ty: values[1], tz: values[2],
+		d:  values[3] * 1e-6,
+		rx: sign * values[4] * 1000 * milliArcSecondInRadians,
+		ry: sign * values[5] * 1000 * milliArcSecondInRadians,
+		rz: sign * values[6] * 1000 * milliArcSecondInRadians,
+		rates: &helmert{
+			tx: values[7], ty: values[8], tz: values[9],
+			d:  values[10] * 1e-6,
+			rx: sign * values[11] * 1000 * milliArcSecondInRadians,
+			ry: sign * values[12] * 1000 * milliArcSecondInRadians,
+			rz: sign * values[13] * 1000 * milliArcSecondInRadians,
+		},
+		epoch: epoch,
+	}
+	return helmertStep{h: h.at(observed)}, nil
+}
+
+func (params projParams) tmerc() (step, error) {
+	el, err := params.ellipsoid()
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := params.take("k_0"); ok {
+		if _, dup := params["k"]; dup {
+			return nil, fmt.Errorf("+k and +k_0 both given")
+		}
+		params["k"] = k
+	}
+	var values [5]float64
+	for i, p := range []struct {
+		key string
+		def float64
+	}{{"lat_0", 0}, {"lon_0", 0}, {"k", 1}, {"x_0", 0}, {"y_0", 0}} {
+		if values[i], err = params.float(p.key, p.def); err != nil {
+			return nil, err
+		}
+	}
+	if units, ok := params.take("units"); ok && units != "m" {
+		return nil, fmt.Errorf("unsupported +units=%s", units)
+	}
+	return projectionStep{
+		proj: newProjection(el, degreesToRadians(values[0]), degreesToRadians(values[1]), values[2], values[3], values[4]),
+	}, nil
+}
+
+// hgridshiftStep shifts OSGB36 longitude and latitude to ETRS89 with a grid
+// transformer, leaving heights unchanged.
+type hgridshiftStep struct {
+	tr *GridTransformer
+}
+
+func (params projParams) hgridshift(resolve gridResolver) (step, error) {
+	tr, err := params.grid(resolve, projHorizontalGrids)
+	if err != nil {
+		return nil, err
+	}
+	return hgridshiftStep{tr: tr}, nil
+}
+
+func (s hgridshiftStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	coord := nationalGridAiry.toPlaneCoord(c.y, c.x)
+	etrs89Coord, err := s.tr.FromNationalGrid(NewOSGB36Coord(coord.easting, coord.northing, 0))
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: degreesToRadians(etrs89Coord.Lon), y: degreesToRadians(etrs89Coord.Lat), z: c.z}, nil
+}
+
+func (s hgridshiftStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	osgb36Coord, err := s.tr.ToNationalGrid(NewETRS89Coord(radiansToDegrees(c.x), radiansToDegrees(c.y), 0))
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	φ, λ, err := nationalGridAiry.fromPlaneCoord(&planeCoord{
+		easting:  osgb36Coord.Easting,
+		northing: osgb36Coord.Northing,
+	})
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: λ, y: φ, z: c.z}, nil
+}
+
+// vgridshiftStep adds a multiple of the geoid separation at an ETRS89 longitude
+// and latitude to heights.
+type vgridshiftStep struct {
+	tr         *GridTransformer
+	multiplier float64
+}
+
+func (params projParams) vgridshift(resolve gridResolver) (step, error) {
+	tr, err := params.grid(resolve, projVerticalGrids)
+	if err != nil {
+		return nil, err
+	}
+	multiplier, err := params.float("multiplier", -1)
+	if err != nil {
+		return nil, err
+	}
+	return vgridshiftStep{tr: tr, multiplier: multiplier}, nil
+}
+
+func (s vgridshiftStep) separation(c pipelineCoord) (float64, error) {
+	ht, err := s.tr.ToOrthometricHeight(radiansToDegrees(c.x), radiansToDegrees(c.y), 0)
+	if err != nil {
+		return 0, err
+	}
+	return ht.GeoidSeparation, nil
+}
+
+func (s vgridshiftStep) forward(c pipelineCoord) (pipelineCoord, error) {
+	n, err := s.separation(c)
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: c.x, y: c.y, z: c.z + s.multiplier*n}, nil
+}
+
+func (s vgridshiftStep) inverse(c pipelineCoord) (pipelineCoord, error) {
+	n, err := s.separation(c)
+	if err != nil {
+		return pipelineCoord{}, err
+	}
+	return pipelineCoord{x: c.x, y: c.y, z: c.z - s.multiplier*n}, nil
+}
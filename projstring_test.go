@@ -0,0 +1,211 @@
+package osgb
+
+import (
+	"strings"
+	"testing"
+)
+
+const osgb36ToWGS84Helmert = "+proj=pipeline +step +proj=unitconvert +xy_in=deg +xy_out=rad +step +proj=cart +ellps=airy " +
+	"+step +proj=helmert +x=446.448 +y=-125.157 +z=542.06 +rx=%s +ry=%s +rz=%s +s=-20.4894 +convention=%s " +
+	"+step +inv +proj=cart +ellps=WGS84 +step +proj=unitconvert +xy_in=rad +xy_out=deg"
+
+func TestParsePipelineHelmert(t *testing.T) {
+	const lon, lat, height = -2.5, 53.5, 100.0
+	expectedLon, expectedLat, expectedHeight, err := DatumOSGB36.ConvertTo(DatumWGS84, lon, lat, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testData := []string{
+		replaceArgs(osgb36ToWGS84Helmert, "0.1502", "0.247", "0.8421", "position_vector"),
+		replaceArgs(osgb36ToWGS84Helmert, "-0.1502", "-0.247", "-0.8421", "coordinate_frame"),
+	}
+	for _, definition := range testData {
+		p, err := ParsePipeline(definition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actualLon, actualLat, actualHeight, err := p.Forward(lon, lat, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkAngle(t, "Lon", expectedLon, actualLon)
+		checkAngle(t, "Lat", expectedLat, actualLat)
+		checkDistance(t, "Height", expectedHeight, actualHeight)
+	}
+}
+
+func replaceArgs(format string, args ...string) string {
+	for _, arg := range args {
+		format = strings.Replace(format, "%s", arg, 1)
+	}
+	return format
+}
+
+func TestParsePipelineSteps(t *testing.T) {
+	testData := []struct {
+		definition string
+		in         [3]float64
+		expected   [3]float64
+	}{
+		{"+proj=axisswap +order=2,1", [3]float64{1, 2, 3}, [3]float64{2, 1, 3}},
+		{"+proj=axisswap +order=1,-2,3", [3]float64{1, 2, 3}, [3]float64{1, -2, 3}},
+		{"proj=unitconvert xy_in=km xy_out=m z_in=m z_out=ft", [3]float64{1, 2, 0.3048}, [3]float64{1000, 2000, 1}},
+		{"+proj=pipeline +ellps=airy +step +proj=unitconvert +xy_in=deg +xy_out=rad " +
+			"+step +proj=tmerc +lat_0=49 +lon_0=-2 +k_0=0.9996012717 +x_0=400000 +y_0=-100000 +units=m",
+			[3]float64{-2, 49, 10}, [3]float64{400000, -100000, 10}},
+		{"+proj=pipeline +inv +ellps=airy +step +proj=unitconvert +xy_in=deg +xy_out=rad " +
+			"+step +proj=tmerc +lat_0=49 +lon_0=-2 +k_0=0.9996012717 +x_0=400000 +y_0=-100000 +units=m",
+			[3]float64{400000, -100000, 10}, [3]float64{-2, 49, 10}},
+	}
+	for _, d := range testData {
+		p, err := ParsePipeline(d.definition)
+		if err != nil {
+			t.Errorf("%s: %s", d.definition, err)
+			continue
+		}
+		x, y, z, err := p.Forward(d.in[0], d.in[1], d.in[2])
+		if err != nil {
+			t.Errorf("%s: %s", d.definition, err)
+			continue
+		}
+		checkDistance(t, d.definition, d.expected[0], x)
+		checkDistance(t, d.definition, d.expected[1], y)
+		checkDistance(t, d.definition, d.expected[2], z)
+		x, y, z, err = p.Inverse(x, y, z)
+		if err != nil {
+			t.Errorf("%s: %s", d.definition, err)
+			continue
+		}
+		checkDistance(t, d.definition, d.in[0], x)
+		checkDistance(t, d.definition, d.in[1], y)
+		checkDistance(t, d.definition, d.in[2], z)
+	}
+}
+
+func TestParsePipelineInverted(t *testing.T) {
+	const steps = "+step +proj=unitconvert +xy_in=deg +xy_out=rad " +
+		"+step +proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 +x_0=400000 +y_0=-100000 +ellps=airy"
+	p, err := ParsePipeline("+proj=pipeline " + steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inverted, err := ParsePipeline("+proj=pipeline +inv " + steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lon, lat, height, err := inverted.Forward(651409.903, 313177.270, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLon, expectedLat, expectedHeight, err := p.Inverse(651409.903, 313177.270, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAngle(t, "lon", 1.7179, lon)
+	checkAngle(t, "lat", 52.6576, lat)
+	checkAngle(t, "lon", expectedLon, lon)
+	checkAngle(t, "lat", expectedLat, lat)
+	checkDistance(t, "height", expectedHeight, height)
+
+	easting, northing, _, err := inverted.Inverse(lon, lat, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "easting", 651409.903, easting)
+	checkDistance(t, "northing", 313177.270, northing)
+}
+
+func TestParsePipelineGrids(t *testing.T) {
+	tr := testTransformer(func(e, n int) GeoidRegion { return Region_UK_MAINLAND })
+	for i := range tr.records {
+		tr.records[i].ostnGeoidHeight = 50
+	}
+	resolve := func(name string, grids map[string]*embeddedGrid) (*GridTransformer, error) {
+		return tr, nil
+	}
+	p, err := parsePipeline("+proj=pipeline +step +proj=unitconvert +xy_in=deg +xy_out=rad "+
+		"+step +proj=vgridshift +grids=uk_os_OSGM15_GB.tif", resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, height, err := p.Forward(-2.5, 53.5, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDistance(t, "Height", 50, height)
+
+	// With zero shifts the grid shift only changes the ellipsoid
+	p, err = parsePipeline("+proj=pipeline +step +proj=unitconvert +xy_in=deg +xy_out=rad "+
+		"+step +proj=hgridshift +grids=OSTN15_NTv2_OSGBtoETRS.gsb +step +proj=unitconvert +xy_in=rad +xy_out=deg", resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lon, lat, _, err := p.Forward(-2.5, 53.5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coord := nationalGridAiry.toPlaneCoord(degreesToRadians(53.5), degreesToRadians(-2.5))
+	expectedLat, expectedLon, err := nationalGridGRS80.fromPlaneCoord(&coord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAngle(t, "Lon", radiansToDegrees(expectedLon), lon)
+	checkAngle(t, "Lat", radiansToDegrees(expectedLat), lat)
+}
+
+func TestParsePipelineInvalid(t *testing.T) {
+	testData := []struct {
+		definition string
+		expected   string
+	}{
+		{"+proj=merc", `unsupported operation "merc"`},
+		{"+proj=cart +ellps=bessel", `unsupported ellipsoid "bessel"`},
+		{"+proj=cart +foo=1", "unsupported parameter +foo"},
+		{"+proj=axisswap +order=3,1", "invalid +order=3,1"},
+		{"+proj=unitconvert +xy_in=deg +xy_out=m", "cannot convert deg to m"},
+		{"+proj=helmert +x=1 +rx=1", "+convention must be"},
+		{"+proj=hgridshift", "missing +grids"},
+		{"+proj=hgridshift +grids=BETA2007.gsb", `unsupported grid "BETA2007.gsb"`},
+		{"+proj=pipeline +step +proj=axisswap +order=2,1 +step +proj=tmerc +lat_0=x", "step 2: tmerc: invalid +lat_0=x"},
+		{"+proj=cart +step +proj=cart", "without +proj=pipeline"},
+	}
+	for _, d := range testData {
+		_, err := ParsePipeline(d.definition)
+		if err == nil {
+			t.Errorf("%s: expected error", d.definition)
+			continue
+		}
+		if !strings.Contains(err.Error(), d.expected) {
+			t.Errorf("%s: expected error containing %q, actual %q", d.definition, d.expected, err)
+		}
+	}
+}
+
+func TestParsePipeline15Data(t *testing.T) {
+	inputs, err := read15OSGBToETRSInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := read15OSGBToETRSOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParsePipeline("+proj=pipeline +step +inv +proj=tmerc +lat_0=49 +lon_0=-2 +k=0.9996012717 " +
+		"+x_0=400000 +y_0=-100000 +ellps=airy +step +proj=hgridshift +grids=uk_os_OSTN15_NTv2_OSGBtoETRS.tif " +
+		"+step +proj=vgridshift +grids=uk_os_OSGM15_GB.tif +multiplier=1 +step +proj=unitconvert +xy_in=rad +xy_out=deg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pointID, input := range inputs {
+		output := outputs[pointID]
+		lon, lat, height, err := p.Forward(input.osgbEasting, input.osgbNorthing, input.orthometricHeight)
+		if err != nil {
+			// Points outside the geoid model are checked by Test15OSGB36ToETRS89Data
+			continue
+		}
+		checkAngle(t, "etrs89 lat", output.etrs89Lat, lat)
+		checkAngle(t, "etrs89 lon", output.etrs89Lon, lon)
+		checkDistance(t, "etrs89 height", output.etrs89Height, height)
+	}
+}
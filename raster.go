@@ -0,0 +1,302 @@
+package osgb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Band selects a value of the transformation grid to export.
+type Band int
+
+const (
+	// BandGeoidHeight is the ETRS89 to ODN height shift (Sg), the geoid separation
+	BandGeoidHeight Band = iota
+	// BandEastShift is the ETRS89 to OSGB36 easting shift (Se)
+	BandEastShift
+	// BandNorthShift is the ETRS89 to OSGB36 northing shift (Sn)
+	BandNorthShift
+)
+
+var bandNames = map[Band]string{
+	BandGeoidHeight: "geoid height",
+	BandEastShift:   "east shift",
+	BandNorthShift:  "north shift",
+}
+
+func (b Band) String() string {
+	if name, ok := bandNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("Band(%d)", int(b))
+}
+
+// rasterNoData marks grid nodes outside the transformation. It is the
+// value PROJ uses for missing GTX nodes.
+const rasterNoData = -88.8888
+
+func (b Band) value(shiftEast, shiftNorth, geoidHeight float64) (float64, error) {
+	switch b {
+	case BandGeoidHeight:
+		return geoidHeight, nil
+	case BandEastShift:
+		return shiftEast, nil
+	case BandNorthShift:
+		return shiftNorth, nil
+	}
+	return 0, fmt.Errorf("unknown band %d", int(b))
+}
+
+// WriteGTX writes a band of the grid as a GTX file, interpolated on ETRS89 latitudes and longitudes.
+func (tr *GridTransformer) WriteGTX(w io.Writer, band Band, extent GeographicExtent) error {
+	if _, err := band.value(0, 0, 0); err != nil {
+		return err
+	}
+	nRows, nCols, err := extent.dims()
+	if err != nil {
+		return err
+	}
+	if err := tr.acquireGrid(); err != nil {
+		return err
+	}
+	defer tr.releaseGrid()
+
+	bw := bufio.NewWriter(w)
+	header := []interface{}{
+		extent.South, extent.West, extent.LatIncrement, extent.LonIncrement,
+		int32(nRows), int32(nCols),
+	}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.BigEndian, v); err != nil {
+			return err
+		}
+	}
+	// Nodes are written row by row from the south west corner.
+	for row := 0; row < nRows; row++ {
+		lat := extent.South + float64(row)*extent.LatIncrement
+		for col := 0; col < nCols; col++ {
+			lon := extent.West + float64(col)*extent.LonIncrement
+			etrs89Coord := nationalGridGRS80.toPlaneCoord(degreesToRadians(lat), degreesToRadians(lon))
+			value := float32(rasterNoData)
+			if rs, err := tr.lookupShiftRecords(&etrs89Coord); err == nil {
+				shiftEast, shiftNorth, geoidHeight, err := tr.interpolate(&etrs89Coord, &rs)
+				if err != nil {
+					return err
+				}
+				v, _ := band.value(shiftEast, shiftNorth, geoidHeight)
+				value = float32(v)
+			}
+			if err := binary.Write(bw, binary.BigEndian, value); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// TIFF and GeoTIFF tags and field types used by WriteGeoTIFF.
+const (
+	tiffShort  = 3
+	tiffLong   = 4
+	tiffASCII  = 2
+	tiffDouble = 12
+
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagStripOffsets              = 273
+	tagSamplesPerPixel           = 277
+	tagRowsPerStrip              = 278
+	tagStripByteCounts           = 279
+	tagPlanarConfiguration       = 284
+	tagSampleFormat              = 339
+	tagModelPixelScale           = 33550
+	tagModelTiepoint             = 33922
+	tagGeoKeyDirectory           = 34735
+	tagGeoDoubleParams           = 34736
+	tagGeoASCIIParams            = 34737
+	tagGDALNoData                = 42113
+)
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func tiffShorts(tag uint16, values ...uint16) tiffEntry {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, values)
+	return tiffEntry{tag: tag, typ: tiffShort, count: uint32(len(values)), data: buf.Bytes()}
+}
+
+func tiffLongs(tag uint16, values ...uint32) tiffEntry {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, values)
+	return tiffEntry{tag: tag, typ: tiffLong, count: uint32(len(values)), data: buf.Bytes()}
+}
+
+func tiffDoubles(tag uint16, values ...float64) tiffEntry {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, values)
+	return tiffEntry{tag: tag, typ: tiffDouble, count: uint32(len(values)), data: buf.Bytes()}
+}
+
+func tiffString(tag uint16, value string) tiffEntry {
+	data := append([]byte(value), 0)
+	return tiffEntry{tag: tag, typ: tiffASCII, count: uint32(len(data)), data: data}
+}
+
+// geoTIFFKeys returns the GeoKey directory, double parameters and ASCII parameters
+// describing the National Grid projection on the GRS80 ellipsoid, in which the
+// transformation grid nodes are defined. The raster values are at the nodes, so
+// the raster type is PixelIsPoint.
+func geoTIFFKeys() ([]uint16, []float64, string) {
+	const (
+		userDefined          = 32767
+		citation             = "ETRS89 / National Grid Transverse Mercator (OSTN grid)|"
+		linearMetre          = 9001
+		modelTypeProjected   = 1
+		rasterPixelIsPoint   = 2
+		ctTransverseMercator = 1
+		geographicETRS89     = 4258
+	)
+	proj := nationalGridGRS80
+	doubles := []float64{
+		radiansToDegrees(proj.geodeticTrueOrigin.lon),
+		radiansToDegrees(proj.geodeticTrueOrigin.lat),
+		proj.mapTrueOrigin.easting,
+		proj.mapTrueOrigin.northing,
+		proj.scaleFactor,
+	}
+	keys := [][4]uint16{
+		{1024, 0, 1, modelTypeProjected},                    // GTModelTypeGeoKey
+		{1025, 0, 1, rasterPixelIsPoint},                    // GTRasterTypeGeoKey
+		{1026, tagGeoASCIIParams, uint16(len(citation)), 0}, // GTCitationGeoKey
+		{2048, 0, 1, geographicETRS89},                      // GeographicTypeGeoKey
+		{3072, 0, 1, userDefined},                           // ProjectedCSTypeGeoKey
+		{3074, 0, 1, userDefined},                           // ProjectionGeoKey
+		{3075, 0, 1, ctTransverseMercator},                  // ProjCoordTransGeoKey
+		{3076, 0, 1, linearMetre},                           // ProjLinearUnitsGeoKey
+		{3080, tagGeoDoubleParams, 1, 0},                    // ProjNatOriginLongGeoKey
+		{3081, tagGeoDoubleParams, 1, 1},                    // ProjNatOriginLatGeoKey
+		{3082, tagGeoDoubleParams, 1, 2},                    // ProjFalseEastingGeoKey
+		{3083, tagGeoDoubleParams, 1, 3},                    // ProjFalseNorthingGeoKey
+		{3092, tagGeoDoubleParams, 1, 4},                    // ProjScaleAtNatOriginGeoKey
+	}
+	directory := []uint16{1, 1, 0, uint16(len(keys))}
+	for _, key := range keys {
+		directory = append(directory, key[:]...)
+	}
+	return directory, doubles, citation
+}
+
+// WriteGeoTIFF writes a band of the grid as a single band GeoTIFF, georeferenced in the
+// ETRS89 National Grid projection the grid nodes are defined in.
+func (tr *GridTransformer) WriteGeoTIFF(w io.Writer, band Band) error {
+	if _, err := band.value(0, 0, 0); err != nil {
+		return err
+	}
+	if err := tr.acquireGrid(); err != nil {
+		return err
+	}
+	defer tr.releaseGrid()
+
+	// Image rows run from north to south.
+	image := new(bytes.Buffer)
+	image.Grow(nRecords * 4)
+	for northIndex := nNorthIndices - 1; northIndex >= 0; northIndex-- {
+		for eastIndex := 0; eastIndex < nEastIndices; eastIndex++ {
+			value := float32(rasterNoData)
+			if rec, err := tr.lookupShiftRecord(uint32(eastIndex), uint32(northIndex)); err == nil {
+				v, _ := band.value(rec.ostnEastShift, rec.ostnNorthShift, rec.ostnGeoidHeight)
+				value = float32(v)
+			}
+			binary.Write(image, binary.LittleEndian, math.Float32bits(value))
+		}
+	}
+
+	directory, doubles, citation := geoTIFFKeys()
+	entries := []tiffEntry{
+		tiffLongs(tagImageWidth, nEastIndices),
+		tiffLongs(tagImageLength, nNorthIndices),
+		tiffShorts(tagBitsPerSample, 32),
+		tiffShorts(tagCompression, 1),
+		tiffShorts(tagPhotometricInterpretation, 1),
+		tiffLongs(tagStripOffsets, 0),
+		tiffShorts(tagSamplesPerPixel, 1),
+		tiffLongs(tagRowsPerStrip, nNorthIndices),
+		tiffLongs(tagStripByteCounts, uint32(image.Len())),
+		tiffShorts(tagPlanarConfiguration, 1),
+		tiffShorts(tagSampleFormat, 3),
+		tiffDoubles(tagModelPixelScale, 1000, 1000, 0),
+		tiffDoubles(tagModelTiepoint, 0, 0, 0, 0, float64((nNorthIndices-1)*1000), 0),
+		tiffShorts(tagGeoKeyDirectory, directory...),
+		tiffDoubles(tagGeoDoubleParams, doubles...),
+		tiffString(tagGeoASCIIParams, citation),
+		tiffString(tagGDALNoData, fmt.Sprint(rasterNoData)),
+	}
+	return writeTIFF(w, entries, image.Bytes())
+}
+
+// writeTIFF writes a little endian TIFF with a single image directory, whose
+// entries must be sorted by tag, and a single strip of image data.
+func writeTIFF(w io.Writer, entries []tiffEntry, image []byte) error {
+	const headerSize = 8
+	ifdSize := 2 + 12*len(entries) + 4
+
+	// Lay out values that don't fit in their directory entry after the directory.
+	offsets := make([]uint32, len(entries))
+	offset := uint32(headerSize + ifdSize)
+	for i, e := range entries {
+		if len(e.data) > 4 {
+			offset += offset % 2
+			offsets[i] = offset
+			offset += uint32(len(e.data))
+		}
+	}
+	offset += offset % 2
+	for i, e := range entries {
+		if e.tag == tagStripOffsets {
+			entries[i] = tiffLongs(tagStripOffsets, offset)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("II")
+	binary.Write(buf, binary.LittleEndian, uint16(42))
+	binary.Write(buf, binary.LittleEndian, uint32(headerSize))
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for i, e := range entries {
+		binary.Write(buf, binary.LittleEndian, e.tag)
+		binary.Write(buf, binary.LittleEndian, e.typ)
+		binary.Write(buf, binary.LittleEndian, e.count)
+		if len(e.data) > 4 {
+			binary.Write(buf, binary.LittleEndian, offsets[i])
+			continue
+		}
+		value := make([]byte, 4)
+		copy(value, e.data)
+		buf.Write(value)
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	for i, e := range entries {
+		if len(e.data) > 4 {
+			buf.Write(make([]byte, int(offsets[i])-buf.Len()))
+			buf.Write(e.data)
+		}
+	}
+	buf.Write(make([]byte, int(offset)-buf.Len()))
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(image)
+	return err
+}
@@ -0,0 +1,104 @@
+package osgb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// rasterTestTransformer returns a transformer whose geoid heights increase
+// by a metre per kilometre east, with the western half of the grid outside
+// the transformation.
+func rasterTestTransformer() *GridTransformer {
+	tr := testTransformer(func(e, n int) GeoidRegion {
+		if e < nEastIndices/2 {
+			return Region_OUTSIDE_TRANSFORMATION
+		}
+		return Region_UK_MAINLAND
+	})
+	for i := range tr.records {
+		tr.records[i].ostnGeoidHeight = float64(tr.records[i].etrs89Easting) / 1000
+		tr.records[i].ostnEastShift = 100
+	}
+	return tr
+}
+
+func TestWriteGTX(t *testing.T) {
+	tr := rasterTestTransformer()
+	extent := GeographicExtent{South: 53, North: 54, West: -3, East: 1, LatIncrement: 0.5, LonIncrement: 1}
+	var buf bytes.Buffer
+	if err := tr.WriteGTX(&buf, BandGeoidHeight, extent); err != nil {
+		t.Fatal(err)
+	}
+	var header struct {
+		Lat, Lon, LatInc, LonInc float64
+		Rows, Cols               int32
+	}
+	if err := binary.Read(&buf, binary.BigEndian, &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Lat != 53 || header.Lon != -3 || header.Rows != 3 || header.Cols != 5 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	values := make([]float32, header.Rows*header.Cols)
+	if err := binary.Read(&buf, binary.BigEndian, values); err != nil {
+		t.Fatal(err)
+	}
+	// The western nodes are outside the transformation
+	if values[0] != float32(rasterNoData) {
+		t.Errorf("expected no data at south west node, actual %f", values[0])
+	}
+	// The geoid height at the eastern node is its easting in kilometres
+	coord := nationalGridGRS80.toPlaneCoord(degreesToRadians(53), degreesToRadians(1))
+	checkDistance(t, "GeoidHeight", coord.easting/1000, float64(values[4]))
+}
+
+func TestWriteGeoTIFF(t *testing.T) {
+	tr := rasterTestTransformer()
+	var buf bytes.Buffer
+	if err := tr.WriteGeoTIFF(&buf, BandEastShift); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != "II*\x00" {
+		t.Fatalf("unexpected TIFF header %q", data[:4])
+	}
+
+	// Read the image directory
+	ifd := binary.LittleEndian.Uint32(data[4:])
+	nEntries := int(binary.LittleEndian.Uint16(data[ifd:]))
+	values := map[uint16]uint32{}
+	for i := 0; i < nEntries; i++ {
+		entry := data[int(ifd)+2+12*i:]
+		values[binary.LittleEndian.Uint16(entry)] = binary.LittleEndian.Uint32(entry[8:])
+	}
+	if values[tagImageWidth] != nEastIndices || values[tagImageLength] != nNorthIndices {
+		t.Errorf("expected %dx%d image, actual %dx%d", nEastIndices, nNorthIndices, values[tagImageWidth], values[tagImageLength])
+	}
+	if _, ok := values[tagGeoKeyDirectory]; !ok {
+		t.Error("expected GeoKey directory")
+	}
+
+	pixel := func(row, col int) float32 {
+		offset := int(values[tagStripOffsets]) + 4*(row*nEastIndices+col)
+		return math.Float32frombits(binary.LittleEndian.Uint32(data[offset:]))
+	}
+	if actual := pixel(0, 0); actual != float32(rasterNoData) {
+		t.Errorf("expected no data at north west node, actual %f", actual)
+	}
+	if actual := pixel(nNorthIndices-1, nEastIndices-1); actual != 100 {
+		t.Errorf("expected east shift of 100 at south east node, actual %f", actual)
+	}
+}
+
+func TestWriteRasterInvalid(t *testing.T) {
+	tr := rasterTestTransformer()
+	var buf bytes.Buffer
+	if err := tr.WriteGeoTIFF(&buf, Band(3)); err == nil {
+		t.Error("expected error for unknown band")
+	}
+	if err := tr.WriteGTX(&buf, BandGeoidHeight, GeographicExtent{North: 1, East: 1}); err == nil {
+		t.Error("expected error for invalid extent")
+	}
+}
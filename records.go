@@ -3,14 +3,19 @@ package osgb
 import (
 	"bytes"
 	"encoding/csv"
+	"fmt"
 	"io"
-	"log"
 	"math"
 	"strconv"
 
 	"github.com/mjjbell/go-osgb/internal/data"
 )
 
+const (
+	nRecordFields = 7
+	nRecords      = nEastIndices * nNorthIndices
+)
+
 type record struct {
 	recordNo        uint32
 	etrs89Easting   uint32
@@ -18,59 +23,83 @@ type record struct {
 	ostnEastShift   float64
 	ostnNorthShift  float64
 	ostnGeoidHeight float64
-	geoidRegion     geoidRegion
+	geoidRegion     GeoidRegion
 }
 
 func readRecords(translationVectorFile string) ([]record, error) {
-
-	res := []record{}
-
 	data, err := data.Asset(translationVectorFile)
 	if err != nil {
 		return nil, err
 	}
+	return parseRecords(bytes.NewReader(data))
+}
+
+// parseRecords reads a translation vector file and validates that it
+// describes the complete 1km grid, one record per grid node in ascending
+// record number order.
+func parseRecords(rd io.Reader) ([]record, error) {
 
-	r := csv.NewReader(bytes.NewReader(data))
+	res := make([]record, 0, nRecords)
+
+	r := csv.NewReader(rd)
+	r.FieldsPerRecord = nRecordFields
 	// Read header
 	if _, err := r.Read(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read grid header: %s", err)
 	}
+	line := 1
 	for {
 		rec, err := r.Read()
 		if err == io.EOF {
 			break
 		}
+		line++
 		if err != nil {
 			return nil, err
 		}
 
 		recordNo, err := strconv.ParseUint(rec[0], 10, 32)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid record number %q", rec[0])
+		}
+		if expected := uint64(len(res) + 1); recordNo != expected {
+			return nil, recordErrorf(line, "record number %d out of sequence, expected %d", recordNo, expected)
+		}
+		if recordNo > nRecords {
+			return nil, recordErrorf(line, "record number %d exceeds grid size of %d records", recordNo, nRecords)
 		}
 		etrs89Easting, err := strconv.ParseUint(rec[1], 10, 32)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid easting %q", rec[1])
 		}
 		etrs89Northing, err := strconv.ParseUint(rec[2], 10, 32)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid northing %q", rec[2])
+		}
+		expectedEasting, expectedNorthing := recordPosition(uint32(recordNo))
+		if etrs89Easting != uint64(expectedEasting) || etrs89Northing != uint64(expectedNorthing) {
+			return nil, recordErrorf(line, "record %d at (%d, %d) does not match grid node (%d, %d)",
+				recordNo, etrs89Easting, etrs89Northing, expectedEasting, expectedNorthing)
 		}
 		ostnEastShift, err := strconv.ParseFloat(rec[3], 64)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid east shift %q", rec[3])
 		}
 		ostnNorthShift, err := strconv.ParseFloat(rec[4], 64)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid north shift %q", rec[4])
 		}
 		ostnGeoidHeight, err := strconv.ParseFloat(rec[5], 64)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid geoid height %q", rec[5])
 		}
 		geoidDatum, err := strconv.ParseUint(rec[6], 10, 8)
 		if err != nil {
-			return nil, err
+			return nil, recordErrorf(line, "invalid geoid datum flag %q", rec[6])
+		}
+		geoidRegion, err := geoidDatumToRegion(geoidDatum)
+		if err != nil {
+			return nil, recordErrorf(line, "%s", err)
 		}
 
 		res = append(res,
@@ -81,53 +110,95 @@ func readRecords(translationVectorFile string) ([]record, error) {
 				ostnEastShift:   ostnEastShift,
 				ostnNorthShift:  ostnNorthShift,
 				ostnGeoidHeight: ostnGeoidHeight,
-				geoidRegion:     geoidDatumToRegion(geoidDatum),
+				geoidRegion:     geoidRegion,
 			})
 	}
+	if len(res) != nRecords {
+		return nil, fmt.Errorf("grid has %d records, expected %d (%dx%d)", len(res), nRecords, nEastIndices, nNorthIndices)
+	}
 	return res, nil
 }
 
-func (tr *transformer) getShiftRecord(eastIndex, northIndex uint32) (*record, error) {
+func recordErrorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid grid record on line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// recordPosition returns the ETRS89 easting and northing of the grid node
+// a record number refers to.
+func recordPosition(recordNo uint32) (uint32, uint32) {
+	index := recordNo - 1
+	return (index % nEastIndices) * 1000, (index / nEastIndices) * 1000
+}
+
+// record returns the record at an index of the grid, from the parsed records
+// or the mapped grid file. Callers reading a mapped grid must hold a reference
+// taken with acquireGrid.
+func (tr *GridTransformer) record(index uint32) record {
+	if tr.mapped != nil {
+		return tr.mapped.record(index)
+	}
+	return tr.records[index]
+}
+
+func (tr *GridTransformer) lookupShiftRecord(eastIndex, northIndex uint32) (record, error) {
 	recordIndex := eastIndex + northIndex*nEastIndices
-	if recordIndex <= 0 || recordIndex >= uint32(len(tr.records)) {
-		return nil, ErrPointOutsidePolygon
+	if recordIndex <= 0 || recordIndex >= nRecords {
+		return record{}, ErrPointOutsidePolygon
 	}
-	rec := &tr.records[recordIndex]
+	rec := tr.record(recordIndex)
 	if rec.geoidRegion == Region_OUTSIDE_BOUNDARY {
-		return nil, ErrPointOutsidePolygon
+		return record{}, ErrPointOutsidePolygon
 	}
 	if rec.geoidRegion == Region_OUTSIDE_TRANSFORMATION {
-		return nil, ErrPointOutsideTransformation
+		return record{}, ErrPointOutsideTransformation
 	}
 	return rec, nil
 }
 
 type shiftRecords struct {
-	s2, s3, s0, s1 *record
+	s2, s3, s0, s1 record
 }
 
-func (tr *transformer) getShiftRecords(etrs89Coord *planeCoord) (*shiftRecords, error) {
-	eastIndex := eastingIndex(etrs89Coord.easting)
-	northIndex := northingIndex(etrs89Coord.northing)
+// getShiftRecords returns the records surrounding an ETRS89 grid position,
+// applying the transformer's offshore policy.
+func (tr *GridTransformer) getShiftRecords(etrs89Coord *planeCoord) (shiftRecords, error) {
+	rs, err := tr.lookupShiftRecords(etrs89Coord)
+	if err != nil {
+		return shiftRecords{}, err
+	}
+	if tr.offshorePolicy != OffshoreTransform && rs.offshore() {
+		return shiftRecords{}, ErrPointOffshore
+	}
+	return rs, nil
+}
+
+// lookupShiftRecords returns the records surrounding an ETRS89 grid position,
+// regardless of the transformer's offshore policy.
+func (tr *GridTransformer) lookupShiftRecords(etrs89Coord *planeCoord) (shiftRecords, error) {
+	return tr.cellShiftRecords(eastingIndex(etrs89Coord.easting), northingIndex(etrs89Coord.northing))
+}
 
-	bl, err := tr.getShiftRecord(eastIndex, northIndex)
+// cellShiftRecords returns the records at the corners of a grid cell,
+// regardless of the transformer's offshore policy.
+func (tr *GridTransformer) cellShiftRecords(eastIndex, northIndex uint32) (shiftRecords, error) {
+	bl, err := tr.lookupShiftRecord(eastIndex, northIndex)
 	if err != nil {
-		return nil, err
+		return shiftRecords{}, err
 	}
-	br, err := tr.getShiftRecord(eastIndex+1, northIndex)
+	br, err := tr.lookupShiftRecord(eastIndex+1, northIndex)
 	if err != nil {
-		return nil, err
+		return shiftRecords{}, err
 	}
-	rt, err := tr.getShiftRecord(eastIndex+1, northIndex+1)
+	rt, err := tr.lookupShiftRecord(eastIndex+1, northIndex+1)
 	if err != nil {
-		return nil, err
+		return shiftRecords{}, err
 	}
-	tl, err := tr.getShiftRecord(eastIndex, northIndex+1)
+	tl, err := tr.lookupShiftRecord(eastIndex, northIndex+1)
 	if err != nil {
-		return nil, err
+		return shiftRecords{}, err
 	}
 
-	return &shiftRecords{
+	return shiftRecords{
 		s0: bl,
 		s1: br,
 		s2: rt,
@@ -135,6 +206,41 @@ func (tr *transformer) getShiftRecords(etrs89Coord *planeCoord) (*shiftRecords,
 	}, nil
 }
 
+// offshore reports whether any of the records is offshore.
+func (rs *shiftRecords) offshore() bool {
+	return rs.s0.geoidRegion == Region_OFFSHORE ||
+		rs.s1.geoidRegion == Region_OFFSHORE ||
+		rs.s2.geoidRegion == Region_OFFSHORE ||
+		rs.s3.geoidRegion == Region_OFFSHORE
+}
+
+// shifts bilinearly interpolates the east, north and geoid height shifts
+// of the records at an ETRS89 grid position.
+func (rs *shiftRecords) shifts(etrs89Coord *planeCoord) (float64, float64, float64) {
+	dx := etrs89Coord.easting - float64(rs.s0.etrs89Easting)
+	t := dx / 1000.0
+	it := 1 - t
+	dy := etrs89Coord.northing - float64(rs.s0.etrs89Northing)
+	u := dy / 1000.0
+	iu := 1 - u
+	shiftEast := it*iu*rs.s0.ostnEastShift +
+		t*iu*rs.s1.ostnEastShift +
+		t*u*rs.s2.ostnEastShift +
+		it*u*rs.s3.ostnEastShift
+
+	shiftNorth := it*iu*rs.s0.ostnNorthShift +
+		t*iu*rs.s1.ostnNorthShift +
+		t*u*rs.s2.ostnNorthShift +
+		it*u*rs.s3.ostnNorthShift
+
+	geoidHeight := it*iu*rs.s0.ostnGeoidHeight +
+		t*iu*rs.s1.ostnGeoidHeight +
+		t*u*rs.s2.ostnGeoidHeight +
+		it*u*rs.s3.ostnGeoidHeight
+
+	return shiftEast, shiftNorth, geoidHeight
+}
+
 func eastingIndex(easting float64) uint32 {
 	return uint32(math.Floor(easting / 1000.0))
 }
@@ -143,10 +249,9 @@ func northingIndex(northing float64) uint32 {
 	return uint32(math.Floor(northing / 1000.0))
 }
 
-func geoidDatumToRegion(id uint64) geoidRegion {
-	if id < 0 || id > 16 {
-		// This is unexpected and we dont't know how to recover from this.
-		log.Fatalf("unexpected geoid datum ID %d", id)
+func geoidDatumToRegion(id uint64) (GeoidRegion, error) {
+	if id > uint64(Region_OUTSIDE_TRANSFORMATION) {
+		return 0, fmt.Errorf("unexpected geoid datum ID %d", id)
 	}
-	return geoidRegion(id)
+	return GeoidRegion(id), nil
 }
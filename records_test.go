@@ -0,0 +1,106 @@
+package osgb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testGridHeader = "SRID,ETRS89_X,ETRS89_Y,ETRS89_OSGB36_EShift,ETRS89_OSGB36_NShift,ETRS89_ODN_HeightShift,Height_Datum_Flag\n"
+
+// testGridLines returns n valid grid lines, starting at record number 1.
+func testGridLines(n int) []string {
+	lines := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		easting, northing := recordPosition(uint32(i))
+		lines = append(lines, fmt.Sprintf("%d,%d,%d,92.139,-81.209,53.484,1", i, easting, northing))
+	}
+	return lines
+}
+
+func TestReadRecords(t *testing.T) {
+	for _, file := range []string{translationVectorFile02, translationVectorFile15} {
+		records, err := readRecords(file)
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+		if len(records) != nRecords {
+			t.Errorf("%s: expected %d records, actual %d", file, nRecords, len(records))
+		}
+	}
+}
+
+func TestParseRecordsInvalid(t *testing.T) {
+	testData := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "region out of range",
+			line:     "4,3000,0,92.139,-81.209,53.484,17",
+			expected: "line 5: unexpected geoid datum ID 17",
+		},
+		{
+			name:     "record out of sequence",
+			line:     "5,4000,0,92.139,-81.209,53.484,1",
+			expected: "line 5: record number 5 out of sequence, expected 4",
+		},
+		{
+			name:     "easting off lattice",
+			line:     "4,3500,0,92.139,-81.209,53.484,1",
+			expected: "line 5: record 4 at (3500, 0) does not match grid node (3000, 0)",
+		},
+		{
+			name:     "northing off lattice",
+			line:     "4,3000,1000,92.139,-81.209,53.484,1",
+			expected: "line 5: record 4 at (3000, 1000) does not match grid node (3000, 0)",
+		},
+		{
+			name:     "malformed shift",
+			line:     "4,3000,0,92.1x9,-81.209,53.484,1",
+			expected: `line 5: invalid east shift "92.1x9"`,
+		},
+		{
+			name:     "missing field",
+			line:     "4,3000,0,92.139,-81.209,53.484",
+			expected: "line 5",
+		},
+	}
+
+	for _, d := range testData {
+		lines := append(testGridLines(3), d.line)
+		_, err := parseRecords(strings.NewReader(testGridHeader + strings.Join(lines, "\n")))
+		if err == nil {
+			t.Errorf("%s: expected error", d.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), d.expected) {
+			t.Errorf("%s: expected error containing %q, actual %q", d.name, d.expected, err)
+		}
+	}
+}
+
+func TestParseRecordsIncomplete(t *testing.T) {
+	lines := testGridLines(nEastIndices)
+	_, err := parseRecords(strings.NewReader(testGridHeader + strings.Join(lines, "\n")))
+	if err == nil {
+		t.Fatal("expected error for incomplete grid")
+	}
+	expected := fmt.Sprintf("grid has %d records, expected %d", nEastIndices, nRecords)
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error containing %q, actual %q", expected, err)
+	}
+}
+
+func TestParseRecordsComplete(t *testing.T) {
+	lines := testGridLines(nRecords)
+	records, err := parseRecords(strings.NewReader(testGridHeader + strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := records[len(records)-1]
+	if last.etrs89Easting != 700000 || last.etrs89Northing != 1250000 {
+		t.Errorf("expected last record at (700000, 1250000), actual (%d, %d)", last.etrs89Easting, last.etrs89Northing)
+	}
+}
@@ -0,0 +1,81 @@
+package osgb
+
+// ShiftRecord is a record of the transformation grid, as listed in the OS test outputs.
+type ShiftRecord struct {
+	// RecordNo is the record number in the grid file
+	RecordNo uint32
+	// Easting of the grid node in the ETRS89 National Grid projection, in metres
+	Easting float64
+	// Northing of the grid node in the ETRS89 National Grid projection, in metres
+	Northing float64
+	// EastShift is the ETRS89 to OSGB36 easting shift (Se) in metres
+	EastShift float64
+	// NorthShift is the ETRS89 to OSGB36 northing shift (Sn) in metres
+	NorthShift float64
+	// GeoidHeight is the ETRS89 to ODN height shift (Sg) in metres
+	GeoidHeight float64
+	// Region is the geoid datum flag of the record
+	Region GeoidRegion
+}
+
+// GridShifts are the shifts interpolated at a position in the ETRS89 National Grid projection,
+// using the transformer's interpolation.
+type GridShifts struct {
+	// EastShift is the interpolated easting shift (Se) in metres
+	EastShift float64
+	// NorthShift is the interpolated northing shift (Sn) in metres
+	NorthShift float64
+	// GeoidHeight is the interpolated height shift (Sg) in metres
+	GeoidHeight float64
+	// Region is the geoid region nearest the position
+	Region GeoidRegion
+	// Records are the grid records surrounding the position, ordered
+	// S0 to S3: bottom left, bottom right, top right, top left.
+	Records [4]ShiftRecord
+}
+
+// Shifts returns the interpolated grid shifts and surrounding grid records at an
+// ETRS89 easting and northing, for auditing against the OS reference software.
+// The shifts are returned regardless of the offshore policy.
+func (tr *GridTransformer) Shifts(etrs89Easting, etrs89Northing float64) (*GridShifts, error) {
+	etrs89Coord := &planeCoord{
+		easting:  etrs89Easting,
+		northing: etrs89Northing,
+	}
+	if err := tr.acquireGrid(); err != nil {
+		return nil, err
+	}
+	defer tr.releaseGrid()
+	rs, err := tr.lookupShiftRecords(etrs89Coord)
+	if err != nil {
+		return nil, err
+	}
+	shiftEast, shiftNorth, geoidHeight, err := tr.interpolate(etrs89Coord, &rs)
+	if err != nil {
+		return nil, err
+	}
+	return &GridShifts{
+		EastShift:   shiftEast,
+		NorthShift:  shiftNorth,
+		GeoidHeight: geoidHeight,
+		Region:      nearestGeoidRegion(etrs89Coord, &rs),
+		Records: [4]ShiftRecord{
+			rs.s0.shiftRecord(),
+			rs.s1.shiftRecord(),
+			rs.s2.shiftRecord(),
+			rs.s3.shiftRecord(),
+		},
+	}, nil
+}
+
+func (rec *record) shiftRecord() ShiftRecord {
+	return ShiftRecord{
+		RecordNo:    rec.recordNo,
+		Easting:     float64(rec.etrs89Easting),
+		Northing:    float64(rec.etrs89Northing),
+		EastShift:   rec.ostnEastShift,
+		NorthShift:  rec.ostnNorthShift,
+		GeoidHeight: rec.ostnGeoidHeight,
+		Region:      rec.geoidRegion,
+	}
+}
@@ -0,0 +1,53 @@
+package osgb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test15ShiftsData(t *testing.T) {
+	inputs, err := read15ETRSToOSGBInputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := read15ETRSToOSGBOutputData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := NewOSTN15Transformer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pointID, input := range inputs {
+		output, ok := outputs[pointID]
+		if !ok {
+			t.Fatal("missing point ID in output ", pointID)
+		}
+
+		etrs89Coord := nationalGridGRS80.toPlaneCoord(degreesToRadians(input.etrs89Lat), degreesToRadians(input.etrs89Lon))
+		shifts, err := trans.Shifts(etrs89Coord.easting, etrs89Coord.northing)
+		if err != nil {
+			t.Errorf("Unexpected error for point ID %s: %s", pointID, err)
+			continue
+		}
+
+		expected := output.shifts
+		for i, rec := range shifts.Records {
+			name := fmt.Sprintf("%s S%d", pointID, i)
+			if rec.RecordNo != expected.Records[i].RecordNo {
+				t.Errorf("%s: expected record %d, actual %d", name, expected.Records[i].RecordNo, rec.RecordNo)
+			}
+			checkDistance(t, name+" Se", expected.Records[i].EastShift, rec.EastShift)
+			checkDistance(t, name+" Sn", expected.Records[i].NorthShift, rec.NorthShift)
+			checkDistance(t, name+" Sg", expected.Records[i].GeoidHeight, rec.GeoidHeight)
+			checkRegion(t, name+" flag", expected.Records[i].Region, rec.Region)
+		}
+		checkDistance(t, pointID+" Se", expected.EastShift, shifts.EastShift)
+		checkDistance(t, pointID+" Sn", expected.NorthShift, shifts.NorthShift)
+		checkDistance(t, pointID+" Sg", expected.GeoidHeight, shifts.GeoidHeight)
+		checkRegion(t, pointID+" region", output.geoidModelID, shifts.Region)
+	}
+}
@@ -0,0 +1,72 @@
+package osgb
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// ErrInvalidBoundingBox indicates a sub-grid bounding box is empty or lies outside the grid.
+var ErrInvalidBoundingBox = errors.New("invalid bounding box")
+
+const (
+	// subGridEdgeSamples is the number of points sampled along each edge of a
+	// box of longitude and latitude, which curve when projected onto the grid.
+	subGridEdgeSamples = 64
+	// subGridShiftMargin widens a box of OSGB36 positions to cover the ETRS89
+	// positions they are shifted from, which are always less than 1km away.
+	subGridShiftMargin = 1000
+)
+
+// WriteBinarySubGrid writes the part of the grid covering the box between south west
+// and north east ETRS89 positions as a binary grid file. A transformer loading it
+// returns ErrPointOutsideTransformation beyond the grid cells covering the box.
+func (tr *GridTransformer) WriteBinarySubGrid(w io.Writer, sw, ne *ETRS89Coordinate) error {
+	if !(sw.Lon < ne.Lon && sw.Lat < ne.Lat) {
+		return ErrInvalidBoundingBox
+	}
+	minE, minN := math.Inf(1), math.Inf(1)
+	maxE, maxN := math.Inf(-1), math.Inf(-1)
+	for i := 0; i <= subGridEdgeSamples; i++ {
+		f := float64(i) / subGridEdgeSamples
+		lon := sw.Lon + f*(ne.Lon-sw.Lon)
+		lat := sw.Lat + f*(ne.Lat-sw.Lat)
+		for _, c := range [...]ETRS89Coordinate{
+			{Lon: lon, Lat: sw.Lat}, {Lon: lon, Lat: ne.Lat},
+			{Lon: sw.Lon, Lat: lat}, {Lon: ne.Lon, Lat: lat},
+		} {
+			etrs89Coord := etrs89ToPlaneCoord(&c)
+			minE, maxE = math.Min(minE, etrs89Coord.easting), math.Max(maxE, etrs89Coord.easting)
+			minN, maxN = math.Min(minN, etrs89Coord.northing), math.Max(maxN, etrs89Coord.northing)
+		}
+	}
+	return tr.writeSubGrid(w, minE, minN, maxE, maxN)
+}
+
+// WriteBinarySubGridNationalGrid writes the part of the grid covering the box between
+// south west and north east OSGB36 positions as a binary grid file.
+func (tr *GridTransformer) WriteBinarySubGridNationalGrid(w io.Writer, sw, ne *OSGB36Coordinate) error {
+	if !(sw.Easting < ne.Easting && sw.Northing < ne.Northing) {
+		return ErrInvalidBoundingBox
+	}
+	return tr.writeSubGrid(w,
+		sw.Easting-subGridShiftMargin, sw.Northing-subGridShiftMargin,
+		ne.Easting+subGridShiftMargin, ne.Northing+subGridShiftMargin)
+}
+
+// writeSubGrid writes the block of grid nodes covering a box of ETRS89 grid
+// positions as a binary grid file.
+func (tr *GridTransformer) writeSubGrid(w io.Writer, minEasting, minNorthing, maxEasting, maxNorthing float64) error {
+	eastIndex := clampIndex(math.Floor(minEasting/1000), nEastIndices-1)
+	northIndex := clampIndex(math.Floor(minNorthing/1000), nNorthIndices-1)
+	lastEastIndex := clampIndex(math.Floor(maxEasting/1000)+1, nEastIndices-1)
+	lastNorthIndex := clampIndex(math.Floor(maxNorthing/1000)+1, nNorthIndices-1)
+	if lastEastIndex <= eastIndex || lastNorthIndex <= northIndex {
+		return ErrInvalidBoundingBox
+	}
+	return tr.writeGridFile(w, eastIndex, northIndex, lastEastIndex-eastIndex+1, lastNorthIndex-northIndex+1)
+}
+
+func clampIndex(index float64, max uint32) uint32 {
+	return uint32(math.Max(0, math.Min(index, float64(max))))
+}
@@ -0,0 +1,93 @@
+package osgb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSubGrid(t *testing.T) {
+	tr := syntheticTransformer()
+	var full, sub bytes.Buffer
+	if err := tr.WriteBinaryGrid(&full); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.WriteBinarySubGrid(&sub, &ETRS89Coordinate{Lon: -5, Lat: 55.5}, &ETRS89Coordinate{Lon: -2, Lat: 58}); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Len() >= full.Len()/10 {
+		t.Errorf("expected sub-grid much smaller than %d bytes, actual %d", full.Len(), sub.Len())
+	}
+	mapped, err := NewMappedTransformer(writeTestFile(t, sub.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+
+	// The corners and centre of the box
+	for _, c := range []ETRS89Coordinate{
+		{Lon: -5, Lat: 55.5}, {Lon: -2, Lat: 55.5}, {Lon: -2, Lat: 58}, {Lon: -5, Lat: 58}, {Lon: -3.5, Lat: 56.8},
+	} {
+		expected, err := tr.ToNationalGridValue(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := mapped.ToNationalGridValue(c)
+		if err != nil {
+			t.Fatalf("%+v: %v", c, err)
+		}
+		checkDistance(t, "Easting", expected.Easting, actual.Easting)
+		checkDistance(t, "Northing", expected.Northing, actual.Northing)
+		if _, err := mapped.FromNationalGridValue(actual); err != nil {
+			t.Errorf("%+v: %v", actual, err)
+		}
+	}
+	for _, c := range []ETRS89Coordinate{{Lon: -1, Lat: 52}, {Lon: -3.5, Lat: 54}, {Lon: -7, Lat: 57}} {
+		if _, err := mapped.ToNationalGridValue(c); err != ErrPointOutsideTransformation {
+			t.Errorf("%+v: expected %v, actual %v", c, ErrPointOutsideTransformation, err)
+		}
+	}
+}
+
+func TestSubGridNationalGrid(t *testing.T) {
+	tr := syntheticTransformer()
+	sw, ne := &OSGB36Coordinate{Easting: 250000, Northing: 650000}, &OSGB36Coordinate{Easting: 320000, Northing: 720000}
+	var sub bytes.Buffer
+	if err := tr.WriteBinarySubGridNationalGrid(&sub, sw, ne); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := NewMappedTransformer(writeTestFile(t, sub.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+
+	for _, c := range []OSGB36Coordinate{*sw, *ne, {Easting: 250000, Northing: 720000}, {Easting: 285000, Northing: 690000}} {
+		expected, err := tr.FromNationalGridValue(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := mapped.FromNationalGridValue(c)
+		if err != nil {
+			t.Fatalf("%+v: %v", c, err)
+		}
+		checkAngle(t, "Lon", expected.Lon, actual.Lon)
+		checkAngle(t, "Lat", expected.Lat, actual.Lat)
+	}
+	if _, err := mapped.FromNationalGridValue(OSGB36Coordinate{Easting: 400000, Northing: 300000}); err != ErrPointOutsideTransformation {
+		t.Errorf("expected %v, actual %v", ErrPointOutsideTransformation, err)
+	}
+}
+
+func TestSubGridInvalidBoundingBox(t *testing.T) {
+	tr := syntheticTransformer()
+	var buf bytes.Buffer
+	if err := tr.WriteBinarySubGrid(&buf, &ETRS89Coordinate{Lon: -2, Lat: 56}, &ETRS89Coordinate{Lon: -5, Lat: 58}); err != ErrInvalidBoundingBox {
+		t.Errorf("expected %v for reversed box, actual %v", ErrInvalidBoundingBox, err)
+	}
+	if err := tr.WriteBinarySubGridNationalGrid(&buf, &OSGB36Coordinate{Easting: 800000, Northing: 100000}, &OSGB36Coordinate{Easting: 900000, Northing: 200000}); err != ErrInvalidBoundingBox {
+		t.Errorf("expected %v for box outside the grid, actual %v", ErrInvalidBoundingBox, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing written, actual %d bytes", buf.Len())
+	}
+}